@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
+	"net/http"
 	"os"
 
 	"github.com/shini4i/atlantis-emoji-gate/internal/client"
@@ -12,17 +14,78 @@ import (
 )
 
 func main() {
+	explain := flag.Bool("explain", false, "print an explanation of the approval decision")
+	explainFormat := flag.String("explain-format", config.ExplainText, "explanation format: text or json")
+	flag.Parse()
+
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
 
-	cfg, err := config.NewGitlabConfig()
+	cfg, err := config.NewConfig()
 	if err != nil {
-		slog.Error("Error parsing GitLab config", "error", err)
+		slog.Error("Error parsing config", "error", err)
 		os.Exit(1)
 	}
 
+	if *explain {
+		if err := config.ValidateExplainFormat(*explainFormat); err != nil {
+			slog.Error("Error parsing flags", "error", err)
+			os.Exit(1)
+		}
+		cfg.Explain = *explainFormat
+	}
+
 	ctx := context.Background()
-	gitlabClient := client.NewGitlabClient(cfg.URL, cfg.Token)
 	proc := processor.NewProcessor()
 
-	os.Exit(gate.Run(ctx, gitlabClient, cfg, proc))
+	transport, err := client.NewTransport(client.TransportConfig{
+		CABundle:           cfg.CABundle,
+		ClientCert:         cfg.ClientCert,
+		ClientKey:          cfg.ClientKey,
+		ProxyURL:           cfg.ProxyURL,
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	})
+	if err != nil {
+		slog.Error("Error configuring the HTTP transport", "error", err)
+		os.Exit(1)
+	}
+
+	os.Exit(gate.Run(ctx, newClient(cfg, transport), cfg, proc))
+}
+
+// newClient creates the API client for the configured provider, sending its
+// requests through the given transport.
+func newClient(cfg config.Config, transport http.RoundTripper) client.Client {
+	retry := client.DefaultRetryPolicy()
+	retry.MaxAttempts = cfg.RetryMaxAttempts
+	retry.Budget = cfg.RetryBudget
+	opts := []client.Option{client.WithRetryPolicy(retry), client.WithTransport(transport)}
+
+	switch cfg.Provider {
+	case config.ProviderGitHub:
+		return client.NewGithubClient(cfg.URL, cfg.Token, opts...)
+	case config.ProviderGitea:
+		return client.NewGiteaClient(cfg.URL, cfg.Token, opts...)
+	case config.ProviderBitbucket:
+		return client.NewBitbucketClient(cfg.URL, cfg.Token, opts...)
+	default:
+		return client.NewGitlabClientWithAuth(cfg.URL, gitlabAuth(cfg), opts...)
+	}
+}
+
+// gitlabAuth creates the GitLab authentication strategy for the configured
+// mode, reading the token from the token file when one is set.
+func gitlabAuth(cfg config.Config) client.Authenticator {
+	token := client.StaticToken(cfg.Token)
+	if cfg.TokenFile != "" {
+		token = client.FileToken(cfg.TokenFile)
+	}
+
+	switch cfg.GitlabAuthMode {
+	case config.GitlabAuthOAuth:
+		return client.BearerAuth(token)
+	case config.GitlabAuthJobToken:
+		return client.JobTokenAuth(token)
+	default:
+		return client.PrivateTokenAuth(token)
+	}
 }
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TokenSource returns the token to authenticate a request with. It is called
+// for every request, so a source may return a different token each time.
+type TokenSource func() (string, error)
+
+// StaticToken returns a TokenSource that always returns the given token.
+func StaticToken(token string) TokenSource {
+	return func() (string, error) {
+		return token, nil
+	}
+}
+
+// FileToken returns a TokenSource that reads the token from a file on every
+// request, so a token rotated by a secrets agent is picked up without a restart.
+// Surrounding whitespace, such as a trailing newline, is ignored.
+func FileToken(path string) TokenSource {
+	return func() (string, error) {
+		content, err := os.ReadFile(path) // #nosec G304 -- the path is operator configuration
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		token := strings.TrimSpace(string(content))
+		if token == "" {
+			return "", fmt.Errorf("token file '%s' is empty", path)
+		}
+		return token, nil
+	}
+}
+
+// Authenticator adds credentials to the headers of an API request.
+type Authenticator interface {
+	Authenticate(header http.Header) error
+}
+
+// headerAuth sets a single header to the token, optionally preceded by a prefix.
+type headerAuth struct {
+	name   string
+	prefix string
+	token  TokenSource
+}
+
+// Authenticate sets the header to the current token.
+func (a headerAuth) Authenticate(header http.Header) error {
+	token, err := a.token()
+	if err != nil {
+		return err
+	}
+	header.Set(a.name, a.prefix+token)
+	return nil
+}
+
+// PrivateTokenAuth authenticates with a GitLab personal, project or group
+// access token sent in the Private-Token header.
+func PrivateTokenAuth(token TokenSource) Authenticator {
+	return headerAuth{name: "Private-Token", token: token}
+}
+
+// BearerAuth authenticates with an OAuth 2.0 access token sent in the
+// Authorization header.
+func BearerAuth(token TokenSource) Authenticator {
+	return headerAuth{name: "Authorization", prefix: "Bearer ", token: token}
+}
+
+// JobTokenAuth authenticates with a GitLab CI/CD job token sent in the
+// JOB-TOKEN header.
+func JobTokenAuth(token TokenSource) Authenticator {
+	return headerAuth{name: "Job-Token", token: token}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticators(t *testing.T) {
+	testCases := []struct {
+		name       string
+		auth       Authenticator
+		wantHeader string
+		wantValue  string
+	}{
+		{"Private token", PrivateTokenAuth(StaticToken("glpat-secret")), "Private-Token", "glpat-secret"},
+		{"OAuth bearer token", BearerAuth(StaticToken("oauth-secret")), "Authorization", "Bearer oauth-secret"},
+		{"CI job token", JobTokenAuth(StaticToken("job-secret")), "JOB-TOKEN", "job-secret"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tc.wantValue, r.Header.Get(tc.wantHeader))
+				for _, other := range []string{"Private-Token", "Authorization", "JOB-TOKEN"} {
+					if other != tc.wantHeader {
+						assert.Empty(t, r.Header.Get(other))
+					}
+				}
+				_, _ = w.Write([]byte(`{"id":1}`))
+			}))
+			defer server.Close()
+
+			client := NewGitlabClientWithAuth(server.URL, tc.auth)
+			_, err := client.GetProject(context.Background(), "group/project")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFileToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("first-token\n"), 0o600))
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	client := NewGitlabClientWithAuth(server.URL, BearerAuth(FileToken(tokenFile)))
+	_, err := client.GetProject(context.Background(), "group/project")
+	assert.NoError(t, err)
+
+	// A rotated token is picked up by the next request.
+	require.NoError(t, os.WriteFile(tokenFile, []byte("rotated-token"), 0o600))
+	_, err = client.GetProject(context.Background(), "group/project")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Bearer first-token", "Bearer rotated-token"}, received)
+
+	t.Run("empty file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(tokenFile, []byte(" \n"), 0o600))
+		_, err := client.GetProject(context.Background(), "group/project")
+		assert.ErrorContains(t, err, "failed to authenticate request")
+		assert.ErrorContains(t, err, "is empty")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := FileToken(filepath.Join(t.TempDir(), "missing"))()
+		assert.ErrorContains(t, err, "failed to read token file")
+	})
+}
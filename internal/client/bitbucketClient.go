@@ -0,0 +1,520 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bitbucket pull request activity actions that change a reviewer's vote.
+const (
+	bitbucketApproved   = "APPROVED"
+	bitbucketUnapproved = "UNAPPROVED"
+	bitbucketNeedsWork  = "REVIEWED"
+	bitbucketCommented  = "COMMENTED"
+)
+
+// Bitbucket pull request activity actions that set the head commit.
+const (
+	bitbucketOpened   = "OPENED"
+	bitbucketRescoped = "RESCOPED"
+)
+
+// Reviewer votes are reported as the GitLab emojis closest in meaning, so an
+// approval counts for APPROVE_EMOJI=thumbsup and "needs work" can veto an
+// apply with BLOCK_EMOJI=thumbsdown.
+const (
+	bitbucketApprovedEmoji  = "thumbsup"
+	bitbucketNeedsWorkEmoji = "thumbsdown"
+)
+
+// bitbucketBuildStates maps the gate's commit status states to Bitbucket build states.
+var bitbucketBuildStates = map[string]string{
+	CommitStatusPending: "INPROGRESS",
+	CommitStatusSuccess: "SUCCESSFUL",
+	CommitStatusFailed:  "FAILED",
+}
+
+// BitbucketClient implements Client using the Bitbucket Server and Data Center
+// REST API. Bitbucket has no emoji reactions on pull requests, so reviewer
+// votes take their place. Repositories cannot be looked up by ID, so every
+// repository is resolved through GetProject first and its ID is cached.
+type BitbucketClient struct {
+	apiURL string
+	token  string
+	client *http.Client
+
+	mu    sync.Mutex
+	repos map[int]string
+}
+
+// bitbucketUser is a Bitbucket account as returned by the API.
+type bitbucketUser struct {
+	Name string `json:"name"`
+}
+
+// bitbucketPage is a page of a paginated Bitbucket response.
+type bitbucketPage[T any] struct {
+	Values        []T  `json:"values"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+// bitbucketComment is a comment on a pull request.
+type bitbucketComment struct {
+	ID          int           `json:"id"`
+	Version     int           `json:"version"`
+	Text        string        `json:"text"`
+	Author      bitbucketUser `json:"author"`
+	CreatedDate int64         `json:"createdDate"`
+	UpdatedDate int64         `json:"updatedDate"`
+}
+
+// bitbucketActivity is an entry of a pull request activity stream.
+type bitbucketActivity struct {
+	Action      string            `json:"action"`
+	User        bitbucketUser     `json:"user"`
+	CreatedDate int64             `json:"createdDate"`
+	Comment     *bitbucketComment `json:"comment"`
+	// FromHash and PreviousFromHash are the source branch heads after and
+	// before a rescoped activity.
+	FromHash         string `json:"fromHash"`
+	PreviousFromHash string `json:"previousFromHash"`
+}
+
+// bitbucketBuildStatus is a build status attached to a commit.
+type bitbucketBuildStatus struct {
+	State       string `json:"state"`
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+// NewBitbucketClient creates a new BitbucketClient for the instance at the
+// given base URL (e.g. "https://bitbucket.example.com") and HTTP access token.
+func NewBitbucketClient(baseURL, token string, opts ...Option) *BitbucketClient {
+	return &BitbucketClient{
+		apiURL: strings.TrimSuffix(baseURL, "/") + "/rest",
+		token:  token,
+		client: newHTTPClient(opts),
+		repos:  make(map[int]string),
+	}
+}
+
+// do performs an authenticated request against the Bitbucket REST API.
+func (b *BitbucketClient) do(ctx context.Context, method, path string, payload any) ([]byte, http.Header, error) {
+	headers := http.Header{"Authorization": {"Bearer " + b.token}}
+	return doRequest(ctx, b.client, method, fmt.Sprintf("%s/%s", b.apiURL, path), headers, payload)
+}
+
+// send performs a request and decodes the response into the target.
+func (b *BitbucketClient) send(ctx context.Context, method, path string, payload, target any) error {
+	body, _, err := b.do(ctx, method, path, payload)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(body, target)
+}
+
+// bitbucketGetAll sends paginated GET requests and collects the values of
+// every page, following Bitbucket's start and nextPageStart offsets.
+func bitbucketGetAll[T any](ctx context.Context, b *BitbucketClient, basePath string) ([]T, error) {
+	separator := "?"
+	if strings.Contains(basePath, "?") {
+		separator = "&"
+	}
+
+	var all []T
+	start := 0
+	for page := 1; ; page++ {
+		if page > maxPages {
+			return nil, fmt.Errorf("pagination exceeded safety limit of %d pages", maxPages)
+		}
+
+		var result bitbucketPage[T]
+		path := fmt.Sprintf("%s%slimit=%d&start=%d", basePath, separator, maxPerPage, start)
+		if err := b.send(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return nil, err
+		}
+		all = append(all, result.Values...)
+
+		if result.IsLastPage {
+			return all, nil
+		}
+		start = result.NextPageStart
+	}
+}
+
+// repoPath returns the API path of a repository resolved earlier by GetProject.
+func (b *BitbucketClient) repoPath(projectID int) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	path, ok := b.repos[projectID]
+	if !ok {
+		return "", fmt.Errorf("repository %d has not been resolved, call GetProject first", projectID)
+	}
+	return path, nil
+}
+
+// pullRequestPath returns the API path of a pull request.
+func (b *BitbucketClient) pullRequestPath(projectID, mrID int) (string, error) {
+	repo, err := b.repoPath(projectID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/pull-requests/%d", repo, mrID), nil
+}
+
+// GetProject retrieves the repository for the given "PROJECT/repo-slug" path
+// along with its default branch.
+func (b *BitbucketClient) GetProject(ctx context.Context, projectPath string) (*Project, error) {
+	projectKey, slug, found := strings.Cut(projectPath, "/")
+	if !found {
+		return nil, fmt.Errorf("invalid repository path '%s', expected PROJECT/repo", projectPath)
+	}
+	repo := fmt.Sprintf("api/1.0/projects/%s/repos/%s", url.PathEscape(projectKey), url.PathEscape(slug))
+
+	var repository struct {
+		ID int `json:"id"`
+	}
+	if err := b.send(ctx, http.MethodGet, repo, nil, &repository); err != nil {
+		return nil, err
+	}
+
+	var branch struct {
+		DisplayID string `json:"displayId"`
+	}
+	if err := b.send(ctx, http.MethodGet, repo+"/default-branch", nil, &branch); err != nil {
+		return nil, fmt.Errorf("failed to get default branch: %w", err)
+	}
+
+	b.mu.Lock()
+	b.repos[repository.ID] = repo
+	b.mu.Unlock()
+
+	return &Project{ID: repository.ID, DefaultBranch: branch.DisplayID}, nil
+}
+
+// activities lists the activity stream of a pull request, newest first.
+func (b *BitbucketClient) activities(ctx context.Context, projectID, mrID int) ([]bitbucketActivity, error) {
+	pr, err := b.pullRequestPath(projectID, mrID)
+	if err != nil {
+		return nil, err
+	}
+	return bitbucketGetAll[bitbucketActivity](ctx, b, pr+"/activities")
+}
+
+// ListAwardEmojis reports the current vote of every reviewer of the pull
+// request as an emoji: approvals as thumbsup and "needs work" as thumbsdown,
+// timestamped with the activity that cast the vote.
+func (b *BitbucketClient) ListAwardEmojis(ctx context.Context, projectID, mrID int) ([]*AwardEmoji, error) {
+	activities, err := b.activities(ctx, projectID, mrID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Activities are returned newest first, so the first vote seen for a
+	// user is their current one.
+	voted := make(map[string]struct{})
+	var emojis []*AwardEmoji
+	for _, activity := range activities {
+		var name string
+		switch activity.Action {
+		case bitbucketApproved:
+			name = bitbucketApprovedEmoji
+		case bitbucketNeedsWork:
+			name = bitbucketNeedsWorkEmoji
+		case bitbucketUnapproved:
+			// A withdrawn vote leaves no emoji, but still supersedes older votes.
+		default:
+			continue
+		}
+
+		if _, ok := voted[activity.User.Name]; ok {
+			continue
+		}
+		voted[activity.User.Name] = struct{}{}
+
+		if name != "" {
+			emojis = append(emojis, &AwardEmoji{
+				Name:      name,
+				User:      User{Username: activity.User.Name},
+				UpdatedAt: time.UnixMilli(activity.CreatedDate).UTC(),
+			})
+		}
+	}
+	return emojis, nil
+}
+
+// ListApprovals lists the reviewers whose current vote on the specified pull
+// request is an approval. These are the same votes ListAwardEmojis reports as
+// thumbsup reactions.
+func (b *BitbucketClient) ListApprovals(ctx context.Context, projectID, mrID int) ([]*Approval, error) {
+	votes, err := b.ListAwardEmojis(ctx, projectID, mrID)
+	if err != nil {
+		return nil, err
+	}
+
+	var approvals []*Approval
+	for _, vote := range votes {
+		if vote.Name == bitbucketApprovedEmoji {
+			approvals = append(approvals, &Approval{User: vote.User, ApprovedAt: vote.UpdatedAt})
+		}
+	}
+	return approvals, nil
+}
+
+// GetFileContent retrieves the raw content of the specified file at the given ref.
+func (b *BitbucketClient) GetFileContent(ctx context.Context, projectID int, branch, filePath string) (string, error) {
+	repo, err := b.repoPath(projectID)
+	if err != nil {
+		return "", err
+	}
+	body, _, err := b.do(ctx, http.MethodGet, fmt.Sprintf("%s/raw/%s?at=%s", repo, escapePath(filePath), url.QueryEscape(branch)), nil)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// GetLatestCommitTimestamp retrieves the committer timestamp of the most
+// recent commit in the specified pull request. Bitbucket lists pull request
+// commits newest first, so only the first commit is requested.
+func (b *BitbucketClient) GetLatestCommitTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error) {
+	pr, err := b.pullRequestPath(projectID, mrID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var result bitbucketPage[struct {
+		CommitterTimestamp int64 `json:"committerTimestamp"`
+	}]
+	if err := b.send(ctx, http.MethodGet, pr+"/commits?limit=1", nil, &result); err != nil {
+		return time.Time{}, err
+	}
+	if len(result.Values) == 0 {
+		return time.Time{}, fmt.Errorf("no commits found for PR %d", mrID)
+	}
+	return time.UnixMilli(result.Values[0].CommitterTimestamp).UTC(), nil
+}
+
+// GetLatestPushTimestamp retrieves when the specified pull request was last
+// pushed to. Bitbucket records every push, including force-pushes, as a
+// rescoped activity that changes the source branch head. A pull request that
+// was never pushed to since it was opened reports the time it was opened.
+func (b *BitbucketClient) GetLatestPushTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error) {
+	activities, err := b.activities(ctx, projectID, mrID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Activities are returned newest first.
+	for _, activity := range activities {
+		pushed := activity.Action == bitbucketRescoped && activity.FromHash != activity.PreviousFromHash
+		if pushed || activity.Action == bitbucketOpened {
+			return time.UnixMilli(activity.CreatedDate).UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no push found in the activity of PR %d", mrID)
+}
+
+// ListChangedFiles lists the paths of the files changed by the specified pull
+// request. Moved files are listed with their source path as well.
+func (b *BitbucketClient) ListChangedFiles(ctx context.Context, projectID, mrID int) ([]string, error) {
+	pr, err := b.pullRequestPath(projectID, mrID)
+	if err != nil {
+		return nil, err
+	}
+
+	type bitbucketPath struct {
+		ToString string `json:"toString"`
+	}
+	changes, err := bitbucketGetAll[struct {
+		Path    bitbucketPath `json:"path"`
+		SrcPath bitbucketPath `json:"srcPath"`
+	}](ctx, b, pr+"/changes")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(changes))
+	for _, change := range changes {
+		paths = append(paths, change.Path.ToString, change.SrcPath.ToString)
+	}
+	return uniquePaths(paths), nil
+}
+
+// ListGroupMembers is not supported: listing the members of a Bitbucket group
+// requires administrator permissions.
+func (b *BitbucketClient) ListGroupMembers(_ context.Context, groupPath string) ([]*User, error) {
+	return nil, fmt.Errorf("resolving group '%s': %w", groupPath, ErrUnsupported)
+}
+
+// GetCurrentUser retrieves the user the token belongs to. Bitbucket has no
+// endpoint for it, but names the authenticated user in the X-AUSERNAME header
+// of every response.
+func (b *BitbucketClient) GetCurrentUser(ctx context.Context) (*User, error) {
+	_, headers, err := b.do(ctx, http.MethodGet, "api/1.0/application-properties", nil)
+	if err != nil {
+		return nil, err
+	}
+	username := headers.Get("X-AUSERNAME")
+	if username == "" {
+		return nil, fmt.Errorf("bitbucket did not report the user of the token")
+	}
+	return &User{Username: username}, nil
+}
+
+// ListNotes lists all comments on the specified pull request, oldest first.
+func (b *BitbucketClient) ListNotes(ctx context.Context, projectID, mrID int) ([]*Note, error) {
+	activities, err := b.activities(ctx, projectID, mrID)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []*Note
+	for i := len(activities) - 1; i >= 0; i-- {
+		if activities[i].Action == bitbucketCommented && activities[i].Comment != nil {
+			notes = append(notes, activities[i].Comment.toNote())
+		}
+	}
+	return notes, nil
+}
+
+// CreateNote adds a comment to the specified pull request.
+func (b *BitbucketClient) CreateNote(ctx context.Context, projectID, mrID int, body string) (*Note, error) {
+	pr, err := b.pullRequestPath(projectID, mrID)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment bitbucketComment
+	if err := b.send(ctx, http.MethodPost, pr+"/comments", map[string]string{"text": body}, &comment); err != nil {
+		return nil, err
+	}
+	return comment.toNote(), nil
+}
+
+// UpdateNote replaces the text of an existing comment. Bitbucket requires the
+// current comment version, so the comment is fetched first.
+func (b *BitbucketClient) UpdateNote(ctx context.Context, projectID, mrID, noteID int, body string) (*Note, error) {
+	pr, err := b.pullRequestPath(projectID, mrID)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("%s/comments/%d", pr, noteID)
+
+	var current bitbucketComment
+	if err := b.send(ctx, http.MethodGet, path, nil, &current); err != nil {
+		return nil, err
+	}
+
+	var comment bitbucketComment
+	payload := map[string]any{"text": body, "version": current.Version}
+	if err := b.send(ctx, http.MethodPut, path, payload, &comment); err != nil {
+		return nil, err
+	}
+	return comment.toNote(), nil
+}
+
+// toNote converts a Bitbucket comment into a Note.
+func (c bitbucketComment) toNote() *Note {
+	return &Note{
+		ID:        c.ID,
+		Body:      c.Text,
+		Author:    User{Username: c.Author.Name},
+		CreatedAt: time.UnixMilli(c.CreatedDate).UTC(),
+		UpdatedAt: time.UnixMilli(c.UpdatedDate).UTC(),
+	}
+}
+
+// GetMergeRequest retrieves the details of the specified pull request.
+func (b *BitbucketClient) GetMergeRequest(ctx context.Context, projectID, mrID int) (*MergeRequest, error) {
+	pr, err := b.pullRequestPath(projectID, mrID)
+	if err != nil {
+		return nil, err
+	}
+
+	type ref struct {
+		DisplayID    string `json:"displayId"`
+		LatestCommit string `json:"latestCommit"`
+	}
+	var result struct {
+		ID      int `json:"id"`
+		FromRef ref `json:"fromRef"`
+		ToRef   ref `json:"toRef"`
+		Links   struct {
+			Self []struct {
+				Href string `json:"href"`
+			} `json:"self"`
+		} `json:"links"`
+	}
+	if err := b.send(ctx, http.MethodGet, pr, nil, &result); err != nil {
+		return nil, err
+	}
+
+	mr := &MergeRequest{
+		IID:          result.ID,
+		SHA:          result.FromRef.LatestCommit,
+		SourceBranch: result.FromRef.DisplayID,
+		TargetBranch: result.ToRef.DisplayID,
+	}
+	if len(result.Links.Self) > 0 {
+		mr.WebURL = result.Links.Self[0].Href
+	}
+	return mr, nil
+}
+
+// ListCommitStatuses lists the build statuses with the given key attached to
+// a commit, most recent first.
+func (b *BitbucketClient) ListCommitStatuses(ctx context.Context, _ int, sha, name string) ([]*CommitStatus, error) {
+	statuses, err := bitbucketGetAll[bitbucketBuildStatus](ctx, b, "build-status/1.0/commits/"+url.PathEscape(sha))
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*CommitStatus
+	for _, status := range statuses {
+		if status.Key != name {
+			continue
+		}
+		state := CommitStatusPending
+		switch status.State {
+		case "SUCCESSFUL":
+			state = CommitStatusSuccess
+		case "FAILED":
+			state = CommitStatusFailed
+		}
+		matching = append(matching, &CommitStatus{
+			Name:        status.Key,
+			State:       state,
+			Description: status.Description,
+			TargetURL:   status.URL,
+		})
+	}
+	return matching, nil
+}
+
+// SetCommitStatus creates or updates the build status with the given key on a
+// commit. Build statuses are global to a Bitbucket instance, so the
+// repository is not part of the request.
+func (b *BitbucketClient) SetCommitStatus(ctx context.Context, _ int, sha string, status CommitStatus) error {
+	state, ok := bitbucketBuildStates[status.State]
+	if !ok {
+		return fmt.Errorf("unsupported commit status state '%s'", status.State)
+	}
+	payload := map[string]string{
+		"state":       state,
+		"key":         status.Name,
+		"name":        status.Name,
+		"url":         status.TargetURL,
+		"description": status.Description,
+	}
+	_, _, err := b.do(ctx, http.MethodPost, "build-status/1.0/commits/"+url.PathEscape(sha), payload)
+	return err
+}
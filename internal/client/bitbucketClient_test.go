@@ -0,0 +1,401 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bitbucketRepo is the API path the Bitbucket stand-in serves repository 42 from.
+const bitbucketRepo = "/rest/api/1.0/projects/INFRA/repos/terraform"
+
+// newBitbucketServer starts a Bitbucket Server stand-in that serves repository
+// 42 as INFRA/terraform and delegates every other request to the handler.
+func newBitbucketServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer dummyToken", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case bitbucketRepo:
+			_, _ = w.Write([]byte(`{"id":42,"slug":"terraform"}`))
+		case bitbucketRepo + "/default-branch":
+			_, _ = w.Write([]byte(`{"id":"refs/heads/main","displayId":"main"}`))
+		default:
+			handler(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newResolvedBitbucketClient creates a client for the stand-in with repository 42 resolved.
+func newResolvedBitbucketClient(t *testing.T, server *httptest.Server) *BitbucketClient {
+	t.Helper()
+	client := NewBitbucketClient(server.URL, "dummyToken")
+	_, err := client.GetProject(context.Background(), "INFRA/terraform")
+	assert.NoError(t, err)
+	return client
+}
+
+func TestBitbucketClient_GetProject(t *testing.T) {
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL.Path)
+	})
+
+	client := NewBitbucketClient(server.URL+"/", "dummyToken")
+	project, err := client.GetProject(context.Background(), "INFRA/terraform")
+	assert.NoError(t, err)
+	assert.Equal(t, &Project{ID: 42, DefaultBranch: "main"}, project)
+
+	_, err = client.GetProject(context.Background(), "terraform")
+	assert.ErrorContains(t, err, "expected PROJECT/repo")
+}
+
+func TestBitbucketClient_RequiresResolvedRepository(t *testing.T) {
+	client := NewBitbucketClient("https://bitbucket.example.com", "dummyToken")
+	_, err := client.ListAwardEmojis(context.Background(), 42, 7)
+	assert.ErrorContains(t, err, "repository 42 has not been resolved")
+}
+
+func TestBitbucketClient_ListAwardEmojis(t *testing.T) {
+	at := func(hour int) int64 {
+		return time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC).UnixMilli()
+	}
+
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, bitbucketRepo+"/pull-requests/7/activities", r.URL.Path)
+		activities := []bitbucketActivity{
+			{Action: bitbucketApproved, User: bitbucketUser{Name: "alice"}, CreatedDate: at(12)},
+			{Action: bitbucketUnapproved, User: bitbucketUser{Name: "bob"}, CreatedDate: at(11)},
+			{Action: bitbucketCommented, User: bitbucketUser{Name: "carol"}, CreatedDate: at(10)},
+		}
+		isLastPage := true
+		if r.URL.Query().Get("start") == "0" {
+			isLastPage = false
+		} else {
+			activities = []bitbucketActivity{
+				{Action: bitbucketApproved, User: bitbucketUser{Name: "bob"}, CreatedDate: at(9)},
+				{Action: bitbucketNeedsWork, User: bitbucketUser{Name: "dave"}, CreatedDate: at(8)},
+				{Action: bitbucketNeedsWork, User: bitbucketUser{Name: "alice"}, CreatedDate: at(7)},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"values": activities, "isLastPage": isLastPage, "nextPageStart": 3})
+	})
+
+	emojis, err := newResolvedBitbucketClient(t, server).ListAwardEmojis(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []*AwardEmoji{
+		{Name: "thumbsup", User: User{Username: "alice"}, UpdatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{Name: "thumbsdown", User: User{Username: "dave"}, UpdatedAt: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)},
+	}, emojis)
+}
+
+func TestBitbucketClient_ListApprovals(t *testing.T) {
+	approvedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, bitbucketRepo+"/pull-requests/7/activities", r.URL.Path)
+		activities := []bitbucketActivity{
+			{Action: bitbucketApproved, User: bitbucketUser{Name: "alice"}, CreatedDate: approvedAt.UnixMilli()},
+			{Action: bitbucketNeedsWork, User: bitbucketUser{Name: "dave"}, CreatedDate: approvedAt.UnixMilli()},
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"values": activities, "isLastPage": true})
+	})
+
+	approvals, err := newResolvedBitbucketClient(t, server).ListApprovals(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Approval{{User: User{Username: "alice"}, ApprovedAt: approvedAt}}, approvals)
+}
+
+func TestBitbucketClient_GetFileContent(t *testing.T) {
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, bitbucketRepo+"/raw/.bitbucket/CODEOWNERS", r.URL.Path)
+		assert.Equal(t, "release", r.URL.Query().Get("at"))
+		_, _ = w.Write([]byte("* @alice\n"))
+	})
+
+	content, err := newResolvedBitbucketClient(t, server).GetFileContent(context.Background(), 42, "release", ".bitbucket/CODEOWNERS")
+	assert.NoError(t, err)
+	assert.Equal(t, "* @alice\n", content)
+}
+
+func TestBitbucketClient_GetLatestCommitTimestamp(t *testing.T) {
+	t.Run("uses the newest commit", func(t *testing.T) {
+		server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, bitbucketRepo+"/pull-requests/7/commits", r.URL.Path)
+			assert.Equal(t, "1", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"values":[{"id":"abc","committerTimestamp":1704110400000}],"isLastPage":false}`))
+		})
+
+		timestamp, err := newResolvedBitbucketClient(t, server).GetLatestCommitTimestamp(context.Background(), 42, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), timestamp)
+	})
+
+	t.Run("no commits", func(t *testing.T) {
+		server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"values":[],"isLastPage":true}`))
+		})
+
+		_, err := newResolvedBitbucketClient(t, server).GetLatestCommitTimestamp(context.Background(), 42, 7)
+		assert.ErrorContains(t, err, "no commits found for PR 7")
+	})
+}
+
+func TestBitbucketClient_GetLatestPushTimestamp(t *testing.T) {
+	testCases := []struct {
+		name       string
+		activities []bitbucketActivity
+		want       time.Time
+	}{
+		{
+			name: "force-push",
+			activities: []bitbucketActivity{
+				{Action: bitbucketRescoped, FromHash: "new", PreviousFromHash: "old", CreatedDate: 1704196800000},
+				{Action: bitbucketOpened, CreatedDate: 1704110400000},
+			},
+			want: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "ignores a target branch update",
+			activities: []bitbucketActivity{
+				{Action: bitbucketRescoped, FromHash: "new", PreviousFromHash: "new", CreatedDate: 1704283200000},
+				{Action: bitbucketRescoped, FromHash: "new", PreviousFromHash: "old", CreatedDate: 1704196800000},
+			},
+			want: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "never pushed to",
+			activities: []bitbucketActivity{
+				{Action: "APPROVED", CreatedDate: 1704196800000},
+				{Action: bitbucketOpened, CreatedDate: 1704110400000},
+			},
+			want: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, bitbucketRepo+"/pull-requests/7/activities", r.URL.Path)
+				_ = json.NewEncoder(w).Encode(map[string]any{"values": tc.activities, "isLastPage": true})
+			})
+
+			timestamp, err := newResolvedBitbucketClient(t, server).GetLatestPushTimestamp(context.Background(), 42, 7)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, timestamp)
+		})
+	}
+
+	t.Run("no push found", func(t *testing.T) {
+		server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"values":[],"isLastPage":true}`))
+		})
+
+		_, err := newResolvedBitbucketClient(t, server).GetLatestPushTimestamp(context.Background(), 42, 7)
+		assert.ErrorContains(t, err, "no push found in the activity of PR 7")
+	})
+}
+
+func TestBitbucketClient_ListGroupMembers(t *testing.T) {
+	_, err := NewBitbucketClient("https://bitbucket.example.com", "dummyToken").ListGroupMembers(context.Background(), "org/sre")
+	assert.True(t, errors.Is(err, ErrUnsupported))
+}
+
+func TestBitbucketClient_GetCurrentUser(t *testing.T) {
+	t.Run("reads the authenticated user from the response headers", func(t *testing.T) {
+		server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/rest/api/1.0/application-properties", r.URL.Path)
+			w.Header().Set("X-AUSERNAME", "emoji-gate-bot")
+			_, _ = w.Write([]byte(`{"version":"8.19.0"}`))
+		})
+
+		user, err := NewBitbucketClient(server.URL, "dummyToken").GetCurrentUser(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, &User{Username: "emoji-gate-bot"}, user)
+	})
+
+	t.Run("anonymous response", func(t *testing.T) {
+		server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"version":"8.19.0"}`))
+		})
+
+		_, err := NewBitbucketClient(server.URL, "dummyToken").GetCurrentUser(context.Background())
+		assert.ErrorContains(t, err, "did not report the user of the token")
+	})
+}
+
+func TestBitbucketClient_Notes(t *testing.T) {
+	var requests []string
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+strings.TrimPrefix(r.URL.Path, bitbucketRepo))
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/activities"):
+			_, _ = w.Write([]byte(`{"isLastPage":true,"values":[
+				{"action":"COMMENTED","comment":{"id":2,"text":"second","author":{"name":"bob"}}},
+				{"action":"APPROVED","user":{"name":"alice"}},
+				{"action":"COMMENTED","comment":{"id":1,"text":"first","author":{"name":"alice"}}}
+			]}`))
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"id":2,"version":3,"text":"second"}`))
+		default:
+			var payload map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			if r.Method == http.MethodPut {
+				assert.Equal(t, float64(3), payload["version"])
+			}
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(bitbucketComment{ID: 2, Text: payload["text"].(string), Author: bitbucketUser{Name: "bot"}})
+		}
+	})
+
+	client := newResolvedBitbucketClient(t, server)
+
+	notes, err := client.ListNotes(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	if assert.Len(t, notes, 2) {
+		assert.Equal(t, "first", notes[0].Body)
+		assert.Equal(t, "alice", notes[0].Author.Username)
+		assert.Equal(t, "second", notes[1].Body)
+	}
+
+	note, err := client.CreateNote(context.Background(), 42, 7, "created")
+	assert.NoError(t, err)
+	assert.Equal(t, "created", note.Body)
+
+	note, err = client.UpdateNote(context.Background(), 42, 7, 2, "updated")
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", note.Body)
+
+	assert.Equal(t, []string{
+		"GET /pull-requests/7/activities",
+		"POST /pull-requests/7/comments",
+		"GET /pull-requests/7/comments/2",
+		"PUT /pull-requests/7/comments/2",
+	}, requests)
+}
+
+func TestBitbucketClient_GetMergeRequest(t *testing.T) {
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, bitbucketRepo+"/pull-requests/7", r.URL.Path)
+		_, _ = w.Write([]byte(`{
+			"id":7,
+			"fromRef":{"displayId":"feature","latestCommit":"abc"},
+			"toRef":{"displayId":"main","latestCommit":"def"},
+			"links":{"self":[{"href":"https://bitbucket.example.com/projects/INFRA/repos/terraform/pull-requests/7"}]}
+		}`))
+	})
+
+	mr, err := newResolvedBitbucketClient(t, server).GetMergeRequest(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, &MergeRequest{
+		IID:          7,
+		SHA:          "abc",
+		SourceBranch: "feature",
+		TargetBranch: "main",
+		WebURL:       "https://bitbucket.example.com/projects/INFRA/repos/terraform/pull-requests/7",
+	}, mr)
+}
+
+func TestBitbucketClient_ListChangedFiles(t *testing.T) {
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, bitbucketRepo+"/pull-requests/7/changes", r.URL.Path)
+		if r.URL.Query().Get("start") == "" || r.URL.Query().Get("start") == "0" {
+			_, _ = w.Write([]byte(`{"values":[{"path":{"toString":"terraform/main.tf"}}],"isLastPage":false,"nextPageStart":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"values":[{"path":{"toString":"modules/vpc/vpc.tf"},"srcPath":{"toString":"modules/old/vpc.tf"}}],"isLastPage":true}`))
+	})
+
+	paths, err := newResolvedBitbucketClient(t, server).ListChangedFiles(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"terraform/main.tf", "modules/vpc/vpc.tf", "modules/old/vpc.tf"}, paths)
+}
+
+func TestBitbucketClient_CommitStatuses(t *testing.T) {
+	var posted map[string]string
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/build-status/1.0/commits/abc", r.URL.Path)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"isLastPage":true,"values":[
+				{"key":"emoji-gate/terraform","state":"FAILED","description":"vetoed"},
+				{"key":"ci","state":"SUCCESSFUL"},
+				{"key":"emoji-gate/terraform","state":"INPROGRESS"}
+			]}`))
+			return
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	client := newResolvedBitbucketClient(t, server)
+
+	statuses, err := client.ListCommitStatuses(context.Background(), 42, "abc", "emoji-gate/terraform")
+	assert.NoError(t, err)
+	assert.Equal(t, []*CommitStatus{
+		{Name: "emoji-gate/terraform", State: CommitStatusFailed, Description: "vetoed"},
+		{Name: "emoji-gate/terraform", State: CommitStatusPending},
+	}, statuses)
+
+	err = client.SetCommitStatus(context.Background(), 42, "abc", CommitStatus{Name: "emoji-gate/terraform", State: CommitStatusSuccess, TargetURL: "https://pr"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"state":       "SUCCESSFUL",
+		"key":         "emoji-gate/terraform",
+		"name":        "emoji-gate/terraform",
+		"url":         "https://pr",
+		"description": "",
+	}, posted)
+
+	err = client.SetCommitStatus(context.Background(), 42, "abc", CommitStatus{State: "running"})
+	assert.ErrorContains(t, err, "unsupported commit status state")
+}
+
+func TestBitbucketClient_Errors(t *testing.T) {
+	server := newBitbucketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[{"message":"Pull request 7 does not exist."}]}`))
+	})
+
+	client := newResolvedBitbucketClient(t, server)
+	ctx := context.Background()
+
+	_, err := client.ListAwardEmojis(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.GetFileContent(ctx, 42, "main", "CODEOWNERS")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.GetLatestCommitTimestamp(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListNotes(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.CreateNote(ctx, 42, 7, "body")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.UpdateNote(ctx, 42, 7, 1, "body")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.GetMergeRequest(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListCommitStatuses(ctx, 42, "abc", "name")
+	assert.ErrorContains(t, err, "404")
+	err = client.SetCommitStatus(ctx, 42, "abc", CommitStatus{State: CommitStatusPending})
+	assert.ErrorContains(t, err, "404")
+
+	t.Run("default branch lookup fails", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/default-branch") {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":42}`))
+		}))
+		defer failing.Close()
+
+		_, err := NewBitbucketClient(failing.URL, "dummyToken").GetProject(ctx, "INFRA/terraform")
+		assert.ErrorContains(t, err, "failed to get default branch")
+	})
+}
@@ -0,0 +1,408 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultTimeout is the default HTTP client timeout for API requests.
+	defaultTimeout = 30 * time.Second
+	// maxPerPage is the maximum number of items per page for paginated API requests.
+	maxPerPage = 100
+	// maxPages is the safety limit on pages fetched by a single paginated request.
+	maxPages = 100 // 100 pages * 100 items = 10,000 items max
+)
+
+// ErrUnsupported is returned by provider clients for operations the provider's
+// API cannot perform.
+var ErrUnsupported = errors.New("not supported by this provider")
+
+// ErrNotFound matches, through errors.Is, the errors returned when the
+// provider responds that a resource does not exist.
+var ErrNotFound = errors.New("not found")
+
+//go:generate go tool mockgen -destination=mocks/mock_client.go -package=mocks . Client
+
+// Client defines the methods the gate needs from a code hosting provider.
+// Merge requests (GitLab) and pull requests (GitHub) are both referred to as
+// merge requests, and repositories are referred to as projects.
+type Client interface {
+	GetProject(ctx context.Context, projectPath string) (*Project, error)
+	ListAwardEmojis(ctx context.Context, projectID, mrID int) ([]*AwardEmoji, error)
+	ListApprovals(ctx context.Context, projectID, mrID int) ([]*Approval, error)
+	GetFileContent(ctx context.Context, projectID int, branch, filePath string) (string, error)
+	GetLatestCommitTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error)
+	GetLatestPushTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error)
+	ListChangedFiles(ctx context.Context, projectID, mrID int) ([]string, error)
+	ListGroupMembers(ctx context.Context, groupPath string) ([]*User, error)
+	GetCurrentUser(ctx context.Context) (*User, error)
+	ListNotes(ctx context.Context, projectID, mrID int) ([]*Note, error)
+	CreateNote(ctx context.Context, projectID, mrID int, body string) (*Note, error)
+	UpdateNote(ctx context.Context, projectID, mrID, noteID int, body string) (*Note, error)
+	GetMergeRequest(ctx context.Context, projectID, mrID int) (*MergeRequest, error)
+	ListCommitStatuses(ctx context.Context, projectID int, sha, name string) ([]*CommitStatus, error)
+	SetCommitStatus(ctx context.Context, projectID int, sha string, status CommitStatus) error
+}
+
+// Project represents a GitLab project or a GitHub repository.
+type Project struct {
+	ID            int    `json:"id"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// AwardEmoji represents an emoji reaction on a merge request.
+// Names follow GitLab's emoji names (e.g. "thumbsup") for every provider.
+// Approvals from other sources, such as native approvals, are represented as
+// reactions without a name and with the approval source set instead, so that
+// no emoji can pass for them. Paths limits an approval comment to those
+// directories and everything beneath them.
+type AwardEmoji struct {
+	Name      string    `json:"name"`
+	User      User      `json:"user"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Source    string    `json:"-"`
+	Paths     []string  `json:"-"`
+}
+
+// Approval represents a user's approval of a merge request through the
+// provider's own review feature, such as GitLab's "Approve" button.
+// ApprovedAt is zero when the provider does not report it.
+type Approval struct {
+	User       User      `json:"user"`
+	ApprovedAt time.Time `json:"approved_at"`
+}
+
+// User represents a user account.
+type User struct {
+	Username string `json:"username"`
+}
+
+// Note represents a comment on a merge request.
+type Note struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	Author    User      `json:"author"`
+	System    bool      `json:"system"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MergeRequest represents the details of a merge request.
+type MergeRequest struct {
+	IID          int    `json:"iid"`
+	SHA          string `json:"sha"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	WebURL       string `json:"web_url"`
+}
+
+// Commit status states, using GitLab's names for every provider.
+const (
+	CommitStatusPending = "pending"
+	CommitStatusSuccess = "success"
+	CommitStatusFailed  = "failed"
+)
+
+// CommitStatus represents an external status attached to a commit.
+type CommitStatus struct {
+	Name        string `json:"name"`
+	State       string `json:"status"`
+	Description string `json:"description,omitempty"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+// requestFunc performs a request against a provider API. The target is either
+// a path relative to the API root or an absolute URL from a Link header.
+type requestFunc func(ctx context.Context, method, target string, payload any) ([]byte, http.Header, error)
+
+// nextLink extracts the URL of the next page from a Link response header.
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// reactionNames maps the reaction contents of GitHub and Gitea to the GitLab
+// emoji names used throughout the gate, so APPROVE_EMOJI=thumbsup matches "+1".
+// Reactions missing from the map already share GitLab's name.
+var reactionNames = map[string]string{
+	"+1":     "thumbsup",
+	"-1":     "thumbsdown",
+	"laugh":  "laughing",
+	"hooray": "tada",
+}
+
+// providerStatusStates maps the gate's commit status states to the states of
+// GitHub and Gitea, which call a failed status "failure".
+var providerStatusStates = map[string]string{
+	CommitStatusPending: "pending",
+	CommitStatusSuccess: "success",
+	CommitStatusFailed:  "failure",
+}
+
+// emojiName translates a GitHub or Gitea reaction content to a GitLab emoji name.
+func emojiName(content string) string {
+	if name, ok := reactionNames[content]; ok {
+		return name
+	}
+	return content
+}
+
+// commitStatusState translates a GitHub or Gitea commit status state to the
+// gate's states, treating both "failure" and "error" as failed.
+func commitStatusState(state string) string {
+	if state == "failure" || state == "error" {
+		return CommitStatusFailed
+	}
+	return state
+}
+
+// getAllByLink sends paginated GET requests and collects all array results
+// across pages, following the "next" URL of the Link response header as
+// GitHub and Gitea return it. The maximum page size is requested using the
+// provider's page size query parameter.
+func getAllByLink[T any](ctx context.Context, do requestFunc, basePath, pageSizeParam string) ([]T, error) {
+	separator := "?"
+	if strings.Contains(basePath, "?") {
+		separator = "&"
+	}
+
+	var all []T
+	next := fmt.Sprintf("%s%s%s=%d", basePath, separator, pageSizeParam, maxPerPage)
+	for page := 1; next != ""; page++ {
+		if page > maxPages {
+			return nil, fmt.Errorf("pagination exceeded safety limit of %d pages", maxPages)
+		}
+
+		body, headers, err := do(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageItems []T
+		if err := decodeJSON(body, &pageItems); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal page %d: %w", page, err)
+		}
+		all = append(all, pageItems...)
+
+		next = ""
+		if match := nextLink.FindStringSubmatch(headers.Get("Link")); match != nil {
+			next = match[1]
+		}
+	}
+
+	return all, nil
+}
+
+// githubReview is a review of a GitHub or Gitea pull request.
+type githubReview struct {
+	User        githubUser `json:"user"`
+	State       string     `json:"state"`
+	SubmittedAt time.Time  `json:"submitted_at"`
+	// Dismissed is only reported by Gitea, GitHub sets the state to DISMISSED instead.
+	Dismissed bool `json:"dismissed"`
+}
+
+// reviewApprovals returns the users whose latest verdict among the reviews,
+// which are listed oldest first, is an approval. Comments leave the verdict
+// unchanged, while requested changes and dismissals withdraw an approval.
+func reviewApprovals(reviews []githubReview) []*Approval {
+	var approvals []*Approval
+	byUser := make(map[string]*Approval)
+	for _, review := range reviews {
+		username := review.User.Login
+		switch {
+		case review.State == "APPROVED" && !review.Dismissed:
+			if approval, ok := byUser[username]; ok {
+				approval.ApprovedAt = review.SubmittedAt
+				continue
+			}
+			approval := &Approval{User: User{Username: username}, ApprovedAt: review.SubmittedAt}
+			byUser[username] = approval
+			approvals = append(approvals, approval)
+		case review.State == "APPROVED", review.State == "CHANGES_REQUESTED", review.State == "REQUEST_CHANGES", review.State == "DISMISSED":
+			if approval, ok := byUser[username]; ok {
+				delete(byUser, username)
+				approvals = slices.DeleteFunc(approvals, func(a *Approval) bool { return a == approval })
+			}
+		}
+	}
+	return approvals
+}
+
+// githubChangedFile is a file changed by a GitHub or Gitea pull request.
+type githubChangedFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+// changedFilePaths returns the paths of GitHub or Gitea changed files,
+// including the previous path of renamed files.
+func changedFilePaths(files []githubChangedFile) []string {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Filename, file.PreviousFilename)
+	}
+	return uniquePaths(paths)
+}
+
+// uniquePaths drops empty and repeated paths, keeping the first occurrence of
+// each, so a renamed file's old path is listed once next to its new path.
+func uniquePaths(paths []string) []string {
+	unique := make([]string, 0, len(paths))
+	seen := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		if _, ok := seen[p]; ok || p == "" {
+			continue
+		}
+		seen[p] = struct{}{}
+		unique = append(unique, p)
+	}
+	return unique
+}
+
+// escapePath escapes every segment of a slash-separated path, keeping the slashes.
+func escapePath(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// doRequest performs an HTTP request with the given headers, sending the
+// payload as a JSON body when it is not nil, and returns the response body
+// and headers. It is shared by every provider client.
+func doRequest(ctx context.Context, hc *http.Client, method, requestURL string, headers http.Header, payload any) ([]byte, http.Header, error) {
+	var requestBody io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, requestBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			slog.Warn("Failed to close response body", "error", err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("received non-200 response: %d with failed body read: %w", resp.StatusCode, err)
+		}
+		return nil, nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Method:     method,
+			Endpoint:   req.URL.RequestURI(),
+			Message:    errorMessage(body),
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return body, resp.Header, nil
+}
+
+// APIError is returned when the provider API responds with a non-2xx status.
+// Use errors.As to inspect it, or errors.Is with ErrNotFound for 404 responses.
+type APIError struct {
+	StatusCode int    // HTTP status code of the response
+	Method     string // HTTP method of the request
+	Endpoint   string // Request path and query, without the host
+	Message    string // Error message from the response body, or the raw body
+}
+
+// Error describes the request, the status code and the provider's message.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: received non-200 response: %d - %s", e.Method, e.Endpoint, e.StatusCode, e.Message)
+}
+
+// Is reports whether the error is ErrNotFound, which holds for 404 responses.
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// errorMessage extracts the error message from a response body in the formats
+// used by the supported providers: GitLab, GitHub and Gitea send a "message",
+// OAuth failures an "error" with a description and the required scope, and
+// Bitbucket a list of "errors". Other bodies are returned as is.
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Message          json.RawMessage `json:"message"`
+		Error            string          `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+		Scope            string          `json:"scope"`
+		Errors           []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+
+	var message string
+	switch {
+	case len(parsed.Message) > 0 && string(parsed.Message) != "null":
+		// GitLab validation errors are objects, which are kept as compact JSON.
+		if err := json.Unmarshal(parsed.Message, &message); err != nil {
+			message = string(parsed.Message)
+		}
+	case parsed.Error != "":
+		message = parsed.Error
+		if parsed.ErrorDescription != "" {
+			message += ": " + parsed.ErrorDescription
+		}
+		if parsed.Scope != "" {
+			message += fmt.Sprintf(" (required scope: %s)", parsed.Scope)
+		}
+	case len(parsed.Errors) > 0:
+		messages := make([]string, 0, len(parsed.Errors))
+		for _, e := range parsed.Errors {
+			messages = append(messages, e.Message)
+		}
+		message = strings.Join(messages, "; ")
+	default:
+		message = strings.TrimSpace(string(body))
+	}
+	return message
+}
+
+// decodeJSON unmarshals a response body into the target.
+func decodeJSON(body []byte, target any) error {
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
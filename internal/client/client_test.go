@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorMessage(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"GitLab message", `{"message":"404 Project Not Found"}`, "404 Project Not Found"},
+		{"GitLab validation errors", `{"message":{"note":["can't be blank"]}}`, `{"note":["can't be blank"]}`},
+		{
+			"OAuth insufficient scope",
+			`{"error":"insufficient_scope","error_description":"The request requires higher privileges than provided by the access token.","scope":"api read_api"}`,
+			"insufficient_scope: The request requires higher privileges than provided by the access token. (required scope: api read_api)",
+		},
+		{"OAuth error without description", `{"error":"invalid_token"}`, "invalid_token"},
+		{"GitHub message", `{"message":"Bad credentials","documentation_url":"https://docs.github.com/rest"}`, "Bad credentials"},
+		{"Bitbucket errors", `{"errors":[{"message":"Repository does not exist."},{"message":"Try again."}]}`, "Repository does not exist.; Try again."},
+		{"Plain text", "502 Bad Gateway\n", "502 Bad Gateway"},
+		{"Unrecognized JSON", `{"status":"down"}`, `{"status":"down"}`},
+		{"Empty body", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, errorMessage([]byte(tc.body)))
+		})
+	}
+}
+
+func TestReviewApprovals(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC)
+	}
+	review := func(login, state string, hour int) githubReview {
+		return githubReview{User: githubUser{Login: login}, State: state, SubmittedAt: at(hour)}
+	}
+
+	dismissed := review("erin", "APPROVED", 6)
+	dismissed.Dismissed = true
+
+	approvals := reviewApprovals([]githubReview{
+		review("alice", "APPROVED", 1),
+		review("bob", "APPROVED", 2),
+		review("alice", "COMMENTED", 3),
+		review("bob", "CHANGES_REQUESTED", 4),
+		review("carol", "REQUEST_CHANGES", 5),
+		dismissed,
+		review("dave", "APPROVED", 7),
+		review("dave", "APPROVED", 8),
+	})
+	assert.Equal(t, []*Approval{
+		{User: User{Username: "alice"}, ApprovedAt: at(1)},
+		{User: User{Username: "dave"}, ApprovedAt: at(8)},
+	}, approvals)
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 File Not Found"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"403 Forbidden"}`))
+		}
+	}))
+	defer server.Close()
+
+	t.Run("not found", func(t *testing.T) {
+		_, _, err := doRequest(context.Background(), server.Client(), http.MethodGet, server.URL+"/missing?ref=main", nil, nil)
+
+		var apiErr *APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, &APIError{
+				StatusCode: http.StatusNotFound,
+				Method:     http.MethodGet,
+				Endpoint:   "/missing?ref=main",
+				Message:    "404 File Not Found",
+			}, apiErr)
+		}
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.EqualError(t, err, "GET /missing?ref=main: received non-200 response: 404 - 404 File Not Found")
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		_, _, err := doRequest(context.Background(), server.Client(), http.MethodPost, server.URL+"/notes", nil, map[string]string{"body": "hi"})
+
+		var apiErr *APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+			assert.Equal(t, http.MethodPost, apiErr.Method)
+		}
+		assert.NotErrorIs(t, err, ErrNotFound)
+	})
+}
@@ -0,0 +1,472 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GiteaClient implements Client using the Gitea API, which Forgejo shares.
+// Pull requests play the role of merge requests. Gitea addresses repositories
+// by owner and name, so the path of every project ID is cached once resolved.
+type GiteaClient struct {
+	apiURL string
+	token  string
+	client *http.Client
+
+	mu    sync.Mutex
+	repos map[int]string
+}
+
+// giteaUser is a Gitea account as returned by the API.
+type giteaUser struct {
+	Login string `json:"login"`
+}
+
+// giteaRepository is a Gitea repository.
+type giteaRepository struct {
+	ID            int    `json:"id"`
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// giteaReaction is a reaction on a pull request.
+type giteaReaction struct {
+	Content   string    `json:"content"`
+	User      giteaUser `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// giteaComment is a comment on a pull request conversation.
+type giteaComment struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	User      giteaUser `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// giteaCommit is a commit of a pull request.
+type giteaCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// giteaStatus is a commit status.
+type giteaStatus struct {
+	Context     string `json:"context"`
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url"`
+}
+
+// giteaTeam is an organization team.
+type giteaTeam struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewGiteaClient creates a new GiteaClient for the instance at the given base
+// URL (e.g. "https://gitea.example.com") and token.
+func NewGiteaClient(baseURL, token string, opts ...Option) *GiteaClient {
+	return &GiteaClient{
+		apiURL: strings.TrimSuffix(baseURL, "/") + "/api/v1",
+		token:  token,
+		client: newHTTPClient(opts),
+		repos:  make(map[int]string),
+	}
+}
+
+// do performs an authenticated request against the Gitea API. The target is
+// either a path relative to the API root or an absolute URL from a Link header.
+func (g *GiteaClient) do(ctx context.Context, method, target string, payload any) ([]byte, http.Header, error) {
+	requestURL := target
+	if !strings.HasPrefix(target, "https://") && !strings.HasPrefix(target, "http://") {
+		requestURL = fmt.Sprintf("%s/%s", g.apiURL, target)
+	}
+	return doRequest(ctx, g.client, method, requestURL, http.Header{"Authorization": {"token " + g.token}}, payload)
+}
+
+// send performs a request and decodes the response into the target.
+func (g *GiteaClient) send(ctx context.Context, method, path string, payload, target any) error {
+	body, _, err := g.do(ctx, method, path, payload)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(body, target)
+}
+
+// repoPath returns the escaped "owner/name" API path of a repository,
+// looking the repository up by ID when it has not been seen yet.
+func (g *GiteaClient) repoPath(ctx context.Context, projectID int) (string, error) {
+	g.mu.Lock()
+	fullName, ok := g.repos[projectID]
+	g.mu.Unlock()
+	if ok {
+		return "repos/" + escapePath(fullName), nil
+	}
+
+	var repo giteaRepository
+	if err := g.send(ctx, http.MethodGet, fmt.Sprintf("repositories/%d", projectID), nil, &repo); err != nil {
+		return "", fmt.Errorf("failed to resolve repository %d: %w", projectID, err)
+	}
+	g.remember(repo)
+	return "repos/" + escapePath(repo.FullName), nil
+}
+
+// remember caches the path of a repository by its ID.
+func (g *GiteaClient) remember(repo giteaRepository) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.repos[repo.ID] = repo.FullName
+}
+
+// GetProject retrieves the repository for the given "owner/name" path.
+func (g *GiteaClient) GetProject(ctx context.Context, projectPath string) (*Project, error) {
+	var repo giteaRepository
+	if err := g.send(ctx, http.MethodGet, "repos/"+escapePath(projectPath), nil, &repo); err != nil {
+		return nil, err
+	}
+	g.remember(repo)
+	return &Project{ID: repo.ID, DefaultBranch: repo.DefaultBranch}, nil
+}
+
+// ListAwardEmojis lists all reactions on the specified pull request, with
+// reaction contents translated to GitLab emoji names.
+func (g *GiteaClient) ListAwardEmojis(ctx context.Context, projectID, mrID int) ([]*AwardEmoji, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := getAllByLink[giteaReaction](ctx, g.do, fmt.Sprintf("%s/issues/%d/reactions", repo, mrID), "limit")
+	if err != nil {
+		return nil, err
+	}
+
+	emojis := make([]*AwardEmoji, 0, len(reactions))
+	for _, reaction := range reactions {
+		// Gitea reactions cannot be edited, so their creation time is their last update.
+		emojis = append(emojis, &AwardEmoji{
+			Name:      emojiName(reaction.Content),
+			User:      User{Username: reaction.User.Login},
+			UpdatedAt: reaction.CreatedAt,
+		})
+	}
+	return emojis, nil
+}
+
+// ListApprovals lists the users whose latest review of the specified pull
+// request approves it and has not been dismissed.
+func (g *GiteaClient) ListApprovals(ctx context.Context, projectID, mrID int) ([]*Approval, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := getAllByLink[githubReview](ctx, g.do, fmt.Sprintf("%s/pulls/%d/reviews", repo, mrID), "limit")
+	if err != nil {
+		return nil, err
+	}
+	return reviewApprovals(reviews), nil
+}
+
+// GetFileContent retrieves the content of the specified file at the given ref.
+func (g *GiteaClient) GetFileContent(ctx context.Context, projectID int, branch, filePath string) (string, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	var content struct {
+		Content string `json:"content"`
+	}
+	path := fmt.Sprintf("%s/contents/%s?ref=%s", repo, escapePath(filePath), url.QueryEscape(branch))
+	if err := g.send(ctx, http.MethodGet, path, nil, &content); err != nil {
+		return "", err
+	}
+	decodedContent, err := base64.StdEncoding.DecodeString(content.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 file content: %w", err)
+	}
+	return string(decodedContent), nil
+}
+
+// GetLatestCommitTimestamp retrieves the committer timestamp of the most
+// recent commit in the specified pull request.
+func (g *GiteaClient) GetLatestCommitTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	commits, err := getAllByLink[giteaCommit](ctx, g.do, fmt.Sprintf("%s/pulls/%d/commits", repo, mrID), "limit")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(commits) == 0 {
+		return time.Time{}, fmt.Errorf("no commits found for PR %d", mrID)
+	}
+
+	// The commit order differs between Gitea and Forgejo releases, so the
+	// latest committer date is used rather than the position in the list.
+	var latest time.Time
+	for _, commit := range commits {
+		if commit.Commit.Committer.Date.After(latest) {
+			latest = commit.Commit.Committer.Date
+		}
+	}
+	return latest, nil
+}
+
+// GetLatestPushTimestamp retrieves when the specified pull request was last
+// pushed to, from the push events of its timeline, which include force-pushes.
+// A pull request that was never pushed to since it was opened reports its
+// creation time.
+func (g *GiteaClient) GetLatestPushTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	events, err := getAllByLink[struct {
+		Type      string    `json:"type"`
+		CreatedAt time.Time `json:"created_at"`
+	}](ctx, g.do, fmt.Sprintf("%s/issues/%d/timeline", repo, mrID), "limit")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var latest time.Time
+	for _, event := range events {
+		if event.Type == "pull_push" && event.CreatedAt.After(latest) {
+			latest = event.CreatedAt
+		}
+	}
+	if !latest.IsZero() {
+		return latest, nil
+	}
+
+	var pr struct {
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := g.send(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d", repo, mrID), nil, &pr); err != nil {
+		return time.Time{}, err
+	}
+	return pr.CreatedAt, nil
+}
+
+// ListChangedFiles lists the paths of the files changed by the specified pull
+// request. Renamed files are listed with their previous path as well.
+func (g *GiteaClient) ListChangedFiles(ctx context.Context, projectID, mrID int) ([]string, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := getAllByLink[githubChangedFile](ctx, g.do, fmt.Sprintf("%s/pulls/%d/files", repo, mrID), "limit")
+	if err != nil {
+		return nil, err
+	}
+	return changedFilePaths(files), nil
+}
+
+// ListGroupMembers lists the members of a team referenced as "org/team".
+// Gitea teams cannot be nested. Other owners, such as users, are reported as
+// ErrNotFound.
+func (g *GiteaClient) ListGroupMembers(ctx context.Context, groupPath string) ([]*User, error) {
+	org, name, found := strings.Cut(groupPath, "/")
+	if !found || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("'%s' is not an org/team reference: %w", groupPath, ErrNotFound)
+	}
+
+	var result struct {
+		Data []giteaTeam `json:"data"`
+	}
+	path := fmt.Sprintf("orgs/%s/teams/search?q=%s", url.PathEscape(org), url.QueryEscape(name))
+	if err := g.send(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+
+	teamID := 0
+	for _, team := range result.Data {
+		if strings.EqualFold(team.Name, name) {
+			teamID = team.ID
+			break
+		}
+	}
+	if teamID == 0 {
+		return nil, fmt.Errorf("team '%s': %w", groupPath, ErrNotFound)
+	}
+
+	members, err := getAllByLink[giteaUser](ctx, g.do, fmt.Sprintf("teams/%d/members", teamID), "limit")
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*User, 0, len(members))
+	for _, member := range members {
+		users = append(users, &User{Username: member.Login})
+	}
+	return users, nil
+}
+
+// GetCurrentUser retrieves the user the token belongs to.
+func (g *GiteaClient) GetCurrentUser(ctx context.Context) (*User, error) {
+	var user giteaUser
+	if err := g.send(ctx, http.MethodGet, "user", nil, &user); err != nil {
+		return nil, err
+	}
+	return &User{Username: user.Login}, nil
+}
+
+// ListNotes lists all conversation comments on the specified pull request, oldest first.
+func (g *GiteaClient) ListNotes(ctx context.Context, projectID, mrID int) ([]*Note, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := getAllByLink[giteaComment](ctx, g.do, fmt.Sprintf("%s/issues/%d/comments", repo, mrID), "limit")
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]*Note, 0, len(comments))
+	for _, comment := range comments {
+		notes = append(notes, comment.toNote())
+	}
+	return notes, nil
+}
+
+// CreateNote adds a conversation comment to the specified pull request.
+func (g *GiteaClient) CreateNote(ctx context.Context, projectID, mrID int, body string) (*Note, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment giteaComment
+	if err := g.send(ctx, http.MethodPost, fmt.Sprintf("%s/issues/%d/comments", repo, mrID), map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return comment.toNote(), nil
+}
+
+// UpdateNote replaces the body of an existing conversation comment.
+// Gitea addresses comments by ID alone, so the pull request number is unused.
+func (g *GiteaClient) UpdateNote(ctx context.Context, projectID, _, noteID int, body string) (*Note, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment giteaComment
+	if err := g.send(ctx, http.MethodPatch, fmt.Sprintf("%s/issues/comments/%d", repo, noteID), map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return comment.toNote(), nil
+}
+
+// toNote converts a Gitea comment into a Note.
+func (c giteaComment) toNote() *Note {
+	return &Note{
+		ID:        c.ID,
+		Body:      c.Body,
+		Author:    User{Username: c.User.Login},
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// GetMergeRequest retrieves the details of the specified pull request.
+func (g *GiteaClient) GetMergeRequest(ctx context.Context, projectID, mrID int) (*MergeRequest, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+		Head    struct {
+			SHA string `json:"sha"`
+			Ref string `json:"ref"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	}
+	if err := g.send(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d", repo, mrID), nil, &pr); err != nil {
+		return nil, err
+	}
+	return &MergeRequest{
+		IID:          pr.Number,
+		SHA:          pr.Head.SHA,
+		SourceBranch: pr.Head.Ref,
+		TargetBranch: pr.Base.Ref,
+		WebURL:       pr.HTMLURL,
+	}, nil
+}
+
+// ListCommitStatuses lists the statuses with the given context attached to a
+// commit, most recent first.
+func (g *GiteaClient) ListCommitStatuses(ctx context.Context, projectID int, sha, name string) ([]*CommitStatus, error) {
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("%s/commits/%s/statuses?sort=recentupdate", repo, url.PathEscape(sha))
+	statuses, err := getAllByLink[giteaStatus](ctx, g.do, path, "limit")
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*CommitStatus
+	for _, status := range statuses {
+		if status.Context != name {
+			continue
+		}
+		matching = append(matching, &CommitStatus{
+			Name:        status.Context,
+			State:       commitStatusState(status.Status),
+			Description: status.Description,
+			TargetURL:   status.TargetURL,
+		})
+	}
+	return matching, nil
+}
+
+// SetCommitStatus creates a status with the given context on a commit.
+func (g *GiteaClient) SetCommitStatus(ctx context.Context, projectID int, sha string, status CommitStatus) error {
+	state, ok := providerStatusStates[status.State]
+	if !ok {
+		return fmt.Errorf("unsupported commit status state '%s'", status.State)
+	}
+
+	repo, err := g.repoPath(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]string{
+		"state":       state,
+		"context":     status.Name,
+		"description": status.Description,
+	}
+	if status.TargetURL != "" {
+		payload["target_url"] = status.TargetURL
+	}
+	var created giteaStatus
+	return g.send(ctx, http.MethodPost, fmt.Sprintf("%s/statuses/%s", repo, url.PathEscape(sha)), payload, &created)
+}
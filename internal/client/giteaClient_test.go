@@ -0,0 +1,344 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newGiteaServer starts a Gitea stand-in that serves repository 42 as
+// "org/repo" and delegates every other request to the handler.
+func newGiteaServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token dummyToken", r.Header.Get("Authorization"))
+		if r.URL.Path == "/api/v1/repositories/42" || r.URL.Path == "/api/v1/repos/org/repo" {
+			_, _ = w.Write([]byte(`{"id":42,"full_name":"org/repo","default_branch":"main"}`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewGiteaClient(t *testing.T) {
+	assert.Equal(t, "https://gitea.example.com/api/v1", NewGiteaClient("https://gitea.example.com/", "token").apiURL)
+}
+
+func TestGiteaClient_GetProject(t *testing.T) {
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL.Path)
+	})
+
+	project, err := NewGiteaClient(server.URL, "dummyToken").GetProject(context.Background(), "org/repo")
+	assert.NoError(t, err)
+	assert.Equal(t, &Project{ID: 42, DefaultBranch: "main"}, project)
+}
+
+func TestGiteaClient_RepositoryLookupIsCached(t *testing.T) {
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repositories/42":
+			lookups++
+			_, _ = w.Write([]byte(`{"id":42,"full_name":"org/repo"}`))
+		case "/api/v1/repos/org/repo/issues/7/reactions", "/api/v1/repos/org/repo/issues/7/comments":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGiteaClient(server.URL, "dummyToken")
+	_, err := client.ListAwardEmojis(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	_, err = client.ListNotes(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, lookups)
+}
+
+func TestGiteaClient_ListAwardEmojis(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var server *httptest.Server
+	server = newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/repos/org/repo/issues/7/reactions", r.URL.Path)
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/repos/org/repo/issues/7/reactions?limit=100&page=2>; rel="next"`, server.URL))
+			_ = json.NewEncoder(w).Encode([]giteaReaction{{Content: "+1", User: giteaUser{Login: "alice"}, CreatedAt: created}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]giteaReaction{{Content: "heart", User: giteaUser{Login: "bob"}, CreatedAt: created}})
+	})
+
+	emojis, err := NewGiteaClient(server.URL, "dummyToken").ListAwardEmojis(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []*AwardEmoji{
+		{Name: "thumbsup", User: User{Username: "alice"}, UpdatedAt: created},
+		{Name: "heart", User: User{Username: "bob"}, UpdatedAt: created},
+	}, emojis)
+}
+
+func TestGiteaClient_GetFileContent(t *testing.T) {
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/repos/org/repo/contents/.gitea/CODEOWNERS", r.URL.Path)
+		assert.Equal(t, "release", r.URL.Query().Get("ref"))
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": base64.StdEncoding.EncodeToString([]byte("* @alice"))})
+	})
+
+	content, err := NewGiteaClient(server.URL, "dummyToken").GetFileContent(context.Background(), 42, "release", ".gitea/CODEOWNERS")
+	assert.NoError(t, err)
+	assert.Equal(t, "* @alice", content)
+}
+
+func TestGiteaClient_GetLatestCommitTimestamp(t *testing.T) {
+	t.Run("uses the latest committer date", func(t *testing.T) {
+		server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/repos/org/repo/pulls/7/commits", r.URL.Path)
+			_, _ = w.Write([]byte(`[
+				{"sha":"new","commit":{"committer":{"date":"2024-01-01T12:00:00Z"}}},
+				{"sha":"old","commit":{"committer":{"date":"2024-01-01T10:00:00Z"}}}
+			]`))
+		})
+
+		timestamp, err := NewGiteaClient(server.URL, "dummyToken").GetLatestCommitTimestamp(context.Background(), 42, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), timestamp)
+	})
+
+	t.Run("no commits", func(t *testing.T) {
+		server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[]`))
+		})
+
+		_, err := NewGiteaClient(server.URL, "dummyToken").GetLatestCommitTimestamp(context.Background(), 42, 7)
+		assert.ErrorContains(t, err, "no commits found for PR 7")
+	})
+}
+
+func TestGiteaClient_GetLatestPushTimestamp(t *testing.T) {
+	t.Run("uses the latest push event", func(t *testing.T) {
+		server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/repos/org/repo/issues/7/timeline", r.URL.Path)
+			_, _ = w.Write([]byte(`[
+				{"type":"pull_push","created_at":"2024-01-01T12:00:00Z"},
+				{"type":"comment","created_at":"2024-01-03T12:00:00Z"},
+				{"type":"pull_push","created_at":"2024-01-02T12:00:00Z"}
+			]`))
+		})
+
+		timestamp, err := NewGiteaClient(server.URL, "dummyToken").GetLatestPushTimestamp(context.Background(), 42, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), timestamp)
+	})
+
+	t.Run("falls back to the creation of a pull request never pushed to", func(t *testing.T) {
+		server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/repos/org/repo/issues/7/timeline":
+				_, _ = w.Write([]byte(`[{"type":"comment","created_at":"2024-01-03T12:00:00Z"}]`))
+			case "/api/v1/repos/org/repo/pulls/7":
+				_, _ = w.Write([]byte(`{"number":7,"created_at":"2024-01-01T12:00:00Z"}`))
+			default:
+				t.Errorf("unexpected request: %s", r.URL.Path)
+			}
+		})
+
+		timestamp, err := NewGiteaClient(server.URL, "dummyToken").GetLatestPushTimestamp(context.Background(), 42, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), timestamp)
+	})
+}
+
+func TestGiteaClient_ListGroupMembers(t *testing.T) {
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/orgs/org/teams/search":
+			// The search matches substrings, so the exact name is picked from the results.
+			_, _ = w.Write([]byte(`{"ok":true,"data":[{"id":3,"name":"sre-oncall"},{"id":5,"name":"SRE"}]}`))
+		case "/api/v1/teams/5/members":
+			_, _ = w.Write([]byte(`[{"login":"alice"},{"login":"bob"}]`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	})
+
+	client := NewGiteaClient(server.URL, "dummyToken")
+
+	members, err := client.ListGroupMembers(context.Background(), "org/sre")
+	assert.NoError(t, err)
+	assert.Equal(t, []*User{{Username: "alice"}, {Username: "bob"}}, members)
+
+	_, err = client.ListGroupMembers(context.Background(), "org/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = client.ListGroupMembers(context.Background(), "org/parent/child")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = client.ListGroupMembers(context.Background(), "alice")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGiteaClient_GetCurrentUser(t *testing.T) {
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user", r.URL.Path)
+		_, _ = w.Write([]byte(`{"login":"emoji-gate-bot"}`))
+	})
+
+	user, err := NewGiteaClient(server.URL, "dummyToken").GetCurrentUser(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &User{Username: "emoji-gate-bot"}, user)
+}
+
+func TestGiteaClient_Notes(t *testing.T) {
+	var requests []string
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[{"id":1,"body":"hello","user":{"login":"alice"}}]`))
+			return
+		}
+		var payload map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(giteaComment{ID: 2, Body: payload["body"], User: giteaUser{Login: "bot"}})
+	})
+
+	client := NewGiteaClient(server.URL, "dummyToken")
+
+	notes, err := client.ListNotes(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Note{{ID: 1, Body: "hello", Author: User{Username: "alice"}}}, notes)
+
+	note, err := client.CreateNote(context.Background(), 42, 7, "created")
+	assert.NoError(t, err)
+	assert.Equal(t, "created", note.Body)
+
+	note, err = client.UpdateNote(context.Background(), 42, 7, 2, "updated")
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", note.Body)
+
+	assert.Equal(t, []string{
+		"GET /api/v1/repos/org/repo/issues/7/comments",
+		"POST /api/v1/repos/org/repo/issues/7/comments",
+		"PATCH /api/v1/repos/org/repo/issues/comments/2",
+	}, requests)
+}
+
+func TestGiteaClient_GetMergeRequest(t *testing.T) {
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/repos/org/repo/pulls/7", r.URL.Path)
+		_, _ = w.Write([]byte(`{"number":7,"html_url":"https://gitea.example.com/org/repo/pulls/7","head":{"sha":"abc","ref":"feature"},"base":{"ref":"main"}}`))
+	})
+
+	mr, err := NewGiteaClient(server.URL, "dummyToken").GetMergeRequest(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, &MergeRequest{
+		IID:          7,
+		SHA:          "abc",
+		SourceBranch: "feature",
+		TargetBranch: "main",
+		WebURL:       "https://gitea.example.com/org/repo/pulls/7",
+	}, mr)
+}
+
+func TestGiteaClient_ListChangedFiles(t *testing.T) {
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/repos/org/repo/pulls/7/files", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"filename":"terraform/main.tf"},{"filename":"modules/vpc/vpc.tf","previous_filename":"modules/old/vpc.tf"}]`))
+	})
+
+	paths, err := NewGiteaClient(server.URL, "dummyToken").ListChangedFiles(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"terraform/main.tf", "modules/vpc/vpc.tf", "modules/old/vpc.tf"}, paths)
+}
+
+func TestGiteaClient_ListApprovals(t *testing.T) {
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/repos/org/repo/pulls/7/reviews", r.URL.Path)
+		_, _ = w.Write([]byte(`[
+			{"user":{"login":"alice"},"state":"APPROVED","submitted_at":"2024-01-01T12:00:00Z"},
+			{"user":{"login":"bob"},"state":"APPROVED","submitted_at":"2024-01-01T12:00:00Z","dismissed":true}
+		]`))
+	})
+
+	approvals, err := NewGiteaClient(server.URL, "dummyToken").ListApprovals(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Approval{
+		{User: User{Username: "alice"}, ApprovedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}, approvals)
+}
+
+func TestGiteaClient_CommitStatuses(t *testing.T) {
+	var posted map[string]string
+	server := newGiteaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/repos/org/repo/commits/abc/statuses":
+			_, _ = w.Write([]byte(`[
+				{"context":"emoji-gate/terraform","status":"error","description":"vetoed"},
+				{"context":"ci/build","status":"success"}
+			]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/org/repo/statuses/abc":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	client := NewGiteaClient(server.URL, "dummyToken")
+
+	statuses, err := client.ListCommitStatuses(context.Background(), 42, "abc", "emoji-gate/terraform")
+	assert.NoError(t, err)
+	assert.Equal(t, []*CommitStatus{{Name: "emoji-gate/terraform", State: CommitStatusFailed, Description: "vetoed"}}, statuses)
+
+	err = client.SetCommitStatus(context.Background(), 42, "abc", CommitStatus{Name: "emoji-gate/terraform", State: CommitStatusSuccess, TargetURL: "https://ci"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"state": "success", "context": "emoji-gate/terraform", "description": "", "target_url": "https://ci"}, posted)
+
+	err = client.SetCommitStatus(context.Background(), 42, "abc", CommitStatus{State: "running"})
+	assert.ErrorContains(t, err, "unsupported commit status state")
+}
+
+func TestGiteaClient_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"The target couldn't be found."}`))
+	}))
+	defer server.Close()
+
+	client := NewGiteaClient(server.URL, "dummyToken")
+	ctx := context.Background()
+
+	_, err := client.GetProject(ctx, "org/repo")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListAwardEmojis(ctx, 42, 7)
+	assert.ErrorContains(t, err, "failed to resolve repository 42")
+	_, err = client.GetFileContent(ctx, 42, "main", "CODEOWNERS")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.GetLatestCommitTimestamp(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListGroupMembers(ctx, "org/sre")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListNotes(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.CreateNote(ctx, 42, 7, "body")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.UpdateNote(ctx, 42, 7, 1, "body")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.GetMergeRequest(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListCommitStatuses(ctx, 42, "abc", "name")
+	assert.ErrorContains(t, err, "404")
+	err = client.SetCommitStatus(ctx, 42, "abc", CommitStatus{State: CommitStatusPending})
+	assert.ErrorContains(t, err, "404")
+}
@@ -0,0 +1,369 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// githubPublicHost is the hostname of public GitHub, whose API is served from
+// a separate host rather than under /api/v3 as on GitHub Enterprise Server.
+const githubPublicHost = "github.com"
+
+// GithubClient implements Client using the GitHub REST API. Repositories are
+// addressed by their numeric ID, and pull requests play the role of merge requests.
+type GithubClient struct {
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+// githubUser is a GitHub account as returned by the API.
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+// githubReaction is a reaction on a pull request.
+type githubReaction struct {
+	Content   string     `json:"content"`
+	User      githubUser `json:"user"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// githubComment is a comment on a pull request conversation.
+type githubComment struct {
+	ID        int        `json:"id"`
+	Body      string     `json:"body"`
+	User      githubUser `json:"user"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// githubCommit is a commit of a pull request.
+type githubCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// githubStatus is a commit status.
+type githubStatus struct {
+	Context     string `json:"context"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url"`
+}
+
+// NewGithubClient creates a new GithubClient for the given hostname and token.
+// "github.com" uses the public API, any other hostname is treated as GitHub
+// Enterprise Server.
+func NewGithubClient(hostname, token string, opts ...Option) *GithubClient {
+	apiURL := "https://api.github.com"
+	if hostname != githubPublicHost {
+		apiURL = fmt.Sprintf("https://%s/api/v3", hostname)
+	}
+	return &GithubClient{
+		apiURL: apiURL,
+		token:  token,
+		client: newHTTPClient(opts),
+	}
+}
+
+// do performs an authenticated request against the GitHub API. The target is
+// either a path relative to the API root or an absolute URL from a Link header.
+func (g *GithubClient) do(ctx context.Context, method, target string, payload any) ([]byte, http.Header, error) {
+	requestURL := target
+	if !strings.HasPrefix(target, "https://") && !strings.HasPrefix(target, "http://") {
+		requestURL = fmt.Sprintf("%s/%s", g.apiURL, target)
+	}
+	headers := http.Header{
+		"Authorization":        {"Bearer " + g.token},
+		"Accept":               {"application/vnd.github+json"},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}
+	return doRequest(ctx, g.client, method, requestURL, headers, payload)
+}
+
+// send performs a request and decodes the response into the target.
+func (g *GithubClient) send(ctx context.Context, method, path string, payload, target any) error {
+	body, _, err := g.do(ctx, method, path, payload)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(body, target)
+}
+
+// GetProject retrieves the repository for the given "owner/name" path.
+func (g *GithubClient) GetProject(ctx context.Context, projectPath string) (*Project, error) {
+	var project Project
+	if err := g.send(ctx, http.MethodGet, "repos/"+escapePath(projectPath), nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListAwardEmojis lists all reactions on the specified pull request, with
+// reaction contents translated to GitLab emoji names.
+func (g *GithubClient) ListAwardEmojis(ctx context.Context, projectID, mrID int) ([]*AwardEmoji, error) {
+	reactions, err := getAllByLink[githubReaction](ctx, g.do, fmt.Sprintf("repositories/%d/issues/%d/reactions", projectID, mrID), "per_page")
+	if err != nil {
+		return nil, err
+	}
+
+	emojis := make([]*AwardEmoji, 0, len(reactions))
+	for _, reaction := range reactions {
+		// GitHub reactions cannot be edited, so their creation time is their last update.
+		emojis = append(emojis, &AwardEmoji{
+			Name:      emojiName(reaction.Content),
+			User:      User{Username: reaction.User.Login},
+			UpdatedAt: reaction.CreatedAt,
+		})
+	}
+	return emojis, nil
+}
+
+// ListApprovals lists the users whose latest review of the specified pull
+// request approves it.
+func (g *GithubClient) ListApprovals(ctx context.Context, projectID, mrID int) ([]*Approval, error) {
+	reviews, err := getAllByLink[githubReview](ctx, g.do, fmt.Sprintf("repositories/%d/pulls/%d/reviews", projectID, mrID), "per_page")
+	if err != nil {
+		return nil, err
+	}
+	return reviewApprovals(reviews), nil
+}
+
+// GetFileContent retrieves the content of the specified file at the given ref.
+func (g *GithubClient) GetFileContent(ctx context.Context, projectID int, branch, filePath string) (string, error) {
+	var content struct {
+		Content string `json:"content"`
+	}
+	path := fmt.Sprintf("repositories/%d/contents/%s?ref=%s", projectID, escapePath(filePath), url.QueryEscape(branch))
+	if err := g.send(ctx, http.MethodGet, path, nil, &content); err != nil {
+		return "", err
+	}
+	// GitHub wraps the base64 content at 60 characters.
+	decodedContent, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content.Content, "\n", ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 file content: %w", err)
+	}
+	return string(decodedContent), nil
+}
+
+// GetLatestCommitTimestamp retrieves the committer timestamp of the most
+// recent commit in the specified pull request. GitHub lists pull request
+// commits oldest first, so every page is read and the last commit is used.
+func (g *GithubClient) GetLatestCommitTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error) {
+	commits, err := getAllByLink[githubCommit](ctx, g.do, fmt.Sprintf("repositories/%d/pulls/%d/commits", projectID, mrID), "per_page")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(commits) == 0 {
+		return time.Time{}, fmt.Errorf("no commits found for PR %d", mrID)
+	}
+	return commits[len(commits)-1].Commit.Committer.Date, nil
+}
+
+// GetLatestPushTimestamp retrieves when the current head commit of the
+// specified pull request was pushed, from the activity of its head branch,
+// which records pushes and force-pushes. GitHub Enterprise Server releases
+// without the repository activity API report ErrUnsupported, and so do head
+// repositories the token cannot read, since GitHub answers both with a 404.
+func (g *GithubClient) GetLatestPushTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error) {
+	var pr struct {
+		Head struct {
+			SHA  string `json:"sha"`
+			Ref  string `json:"ref"`
+			Repo *struct {
+				ID int `json:"id"`
+			} `json:"repo"`
+		} `json:"head"`
+	}
+	if err := g.send(ctx, http.MethodGet, fmt.Sprintf("repositories/%d/pulls/%d", projectID, mrID), nil, &pr); err != nil {
+		return time.Time{}, err
+	}
+	if pr.Head.Repo == nil {
+		return time.Time{}, fmt.Errorf("head repository of PR %d no longer exists", mrID)
+	}
+
+	// Activities are listed newest first, so the head push is on the first page.
+	var activities []struct {
+		After     string    `json:"after"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+	path := fmt.Sprintf("repositories/%d/activity?ref=%s&per_page=%d", pr.Head.Repo.ID, url.QueryEscape("refs/heads/"+pr.Head.Ref), maxPerPage)
+	if err := g.send(ctx, http.MethodGet, path, nil, &activities); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return time.Time{}, fmt.Errorf("repository activity: %w", ErrUnsupported)
+		}
+		return time.Time{}, err
+	}
+	for _, activity := range activities {
+		if activity.After == pr.Head.SHA {
+			return activity.Timestamp, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no push of head commit %s found in the activity of branch %s", pr.Head.SHA, pr.Head.Ref)
+}
+
+// ListChangedFiles lists the paths of the files changed by the specified pull
+// request. Renamed files are listed with their previous path as well. GitHub
+// lists at most 3000 files per pull request.
+func (g *GithubClient) ListChangedFiles(ctx context.Context, projectID, mrID int) ([]string, error) {
+	files, err := getAllByLink[githubChangedFile](ctx, g.do, fmt.Sprintf("repositories/%d/pulls/%d/files", projectID, mrID), "per_page")
+	if err != nil {
+		return nil, err
+	}
+	return changedFilePaths(files), nil
+}
+
+// ListGroupMembers lists the members of a team referenced as "org/team-slug".
+// GitHub includes the members of child teams in the result. Other owners,
+// such as users, are reported as ErrNotFound.
+func (g *GithubClient) ListGroupMembers(ctx context.Context, groupPath string) ([]*User, error) {
+	org, team, found := strings.Cut(groupPath, "/")
+	if !found {
+		return nil, fmt.Errorf("'%s' is not an org/team reference: %w", groupPath, ErrNotFound)
+	}
+	// Nested teams are referenced by their own slug, the last path segment.
+	team = team[strings.LastIndex(team, "/")+1:]
+
+	members, err := getAllByLink[githubUser](ctx, g.do, fmt.Sprintf("orgs/%s/teams/%s/members", url.PathEscape(org), url.PathEscape(team)), "per_page")
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*User, 0, len(members))
+	for _, member := range members {
+		users = append(users, &User{Username: member.Login})
+	}
+	return users, nil
+}
+
+// GetCurrentUser retrieves the user the token belongs to.
+func (g *GithubClient) GetCurrentUser(ctx context.Context) (*User, error) {
+	var user githubUser
+	if err := g.send(ctx, http.MethodGet, "user", nil, &user); err != nil {
+		return nil, err
+	}
+	return &User{Username: user.Login}, nil
+}
+
+// ListNotes lists all conversation comments on the specified pull request, oldest first.
+func (g *GithubClient) ListNotes(ctx context.Context, projectID, mrID int) ([]*Note, error) {
+	comments, err := getAllByLink[githubComment](ctx, g.do, fmt.Sprintf("repositories/%d/issues/%d/comments", projectID, mrID), "per_page")
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]*Note, 0, len(comments))
+	for _, comment := range comments {
+		notes = append(notes, comment.toNote())
+	}
+	return notes, nil
+}
+
+// CreateNote adds a conversation comment to the specified pull request.
+func (g *GithubClient) CreateNote(ctx context.Context, projectID, mrID int, body string) (*Note, error) {
+	var comment githubComment
+	path := fmt.Sprintf("repositories/%d/issues/%d/comments", projectID, mrID)
+	if err := g.send(ctx, http.MethodPost, path, map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return comment.toNote(), nil
+}
+
+// UpdateNote replaces the body of an existing conversation comment.
+// GitHub addresses comments by ID alone, so the pull request number is unused.
+func (g *GithubClient) UpdateNote(ctx context.Context, projectID, _, noteID int, body string) (*Note, error) {
+	var comment githubComment
+	path := fmt.Sprintf("repositories/%d/issues/comments/%d", projectID, noteID)
+	if err := g.send(ctx, http.MethodPatch, path, map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return comment.toNote(), nil
+}
+
+// toNote converts a GitHub comment into a Note.
+func (c githubComment) toNote() *Note {
+	return &Note{
+		ID:        c.ID,
+		Body:      c.Body,
+		Author:    User{Username: c.User.Login},
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// GetMergeRequest retrieves the details of the specified pull request.
+func (g *GithubClient) GetMergeRequest(ctx context.Context, projectID, mrID int) (*MergeRequest, error) {
+	var pr struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+		Head    struct {
+			SHA string `json:"sha"`
+			Ref string `json:"ref"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	}
+	if err := g.send(ctx, http.MethodGet, fmt.Sprintf("repositories/%d/pulls/%d", projectID, mrID), nil, &pr); err != nil {
+		return nil, err
+	}
+	return &MergeRequest{
+		IID:          pr.Number,
+		SHA:          pr.Head.SHA,
+		SourceBranch: pr.Head.Ref,
+		TargetBranch: pr.Base.Ref,
+		WebURL:       pr.HTMLURL,
+	}, nil
+}
+
+// ListCommitStatuses lists the statuses with the given context attached to a
+// commit, most recent first.
+func (g *GithubClient) ListCommitStatuses(ctx context.Context, projectID int, sha, name string) ([]*CommitStatus, error) {
+	statuses, err := getAllByLink[githubStatus](ctx, g.do, fmt.Sprintf("repositories/%d/commits/%s/statuses", projectID, url.PathEscape(sha)), "per_page")
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*CommitStatus
+	for _, status := range statuses {
+		if status.Context != name {
+			continue
+		}
+		matching = append(matching, &CommitStatus{
+			Name:        status.Context,
+			State:       commitStatusState(status.State),
+			Description: status.Description,
+			TargetURL:   status.TargetURL,
+		})
+	}
+	return matching, nil
+}
+
+// SetCommitStatus creates a status with the given context on a commit.
+func (g *GithubClient) SetCommitStatus(ctx context.Context, projectID int, sha string, status CommitStatus) error {
+	state, ok := providerStatusStates[status.State]
+	if !ok {
+		return fmt.Errorf("unsupported commit status state '%s'", status.State)
+	}
+	payload := map[string]string{
+		"state":       state,
+		"context":     status.Name,
+		"description": status.Description,
+	}
+	if status.TargetURL != "" {
+		payload["target_url"] = status.TargetURL
+	}
+	var created githubStatus
+	return g.send(ctx, http.MethodPost, fmt.Sprintf("repositories/%d/statuses/%s", projectID, url.PathEscape(sha)), payload, &created)
+}
@@ -0,0 +1,374 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestGithubClient creates a test GitHub client pointing to the mock server.
+func newTestGithubClient(serverURL string) *GithubClient {
+	client := NewGithubClient("github.example.com", "dummyToken")
+	client.apiURL = serverURL
+	return client
+}
+
+func TestNewGithubClient(t *testing.T) {
+	assert.Equal(t, "https://api.github.com", NewGithubClient("github.com", "token").apiURL)
+	assert.Equal(t, "https://github.example.com/api/v3", NewGithubClient("github.example.com", "token").apiURL)
+}
+
+func TestGithubClient_GetProject(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/org/repo", r.URL.Path)
+		assert.Equal(t, "Bearer dummyToken", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/vnd.github+json", r.Header.Get("Accept"))
+		_, _ = w.Write([]byte(`{"id":42,"default_branch":"main"}`))
+	}))
+	defer server.Close()
+
+	project, err := newTestGithubClient(server.URL).GetProject(context.Background(), "org/repo")
+	assert.NoError(t, err)
+	assert.Equal(t, &Project{ID: 42, DefaultBranch: "main"}, project)
+}
+
+func TestGithubClient_ListAwardEmojis(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/42/issues/7/reactions", r.URL.Path)
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repositories/42/issues/7/reactions?per_page=100&page=2>; rel="next", <%s/repositories/42/issues/7/reactions?per_page=100&page=2>; rel="last"`, server.URL, server.URL))
+			_ = json.NewEncoder(w).Encode([]githubReaction{{Content: "+1", User: githubUser{Login: "alice"}, CreatedAt: created}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]githubReaction{
+			{Content: "-1", User: githubUser{Login: "bob"}, CreatedAt: created},
+			{Content: "rocket", User: githubUser{Login: "carol"}, CreatedAt: created},
+		})
+	}))
+	defer server.Close()
+
+	emojis, err := newTestGithubClient(server.URL).ListAwardEmojis(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []*AwardEmoji{
+		{Name: "thumbsup", User: User{Username: "alice"}, UpdatedAt: created},
+		{Name: "thumbsdown", User: User{Username: "bob"}, UpdatedAt: created},
+		{Name: "rocket", User: User{Username: "carol"}, UpdatedAt: created},
+	}, emojis)
+}
+
+func TestGithubClient_Pagination_SafetyLimit(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=next>; rel="next"`, server.URL, r.URL.Path))
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	_, err := newTestGithubClient(server.URL).ListAwardEmojis(context.Background(), 42, 7)
+	assert.ErrorContains(t, err, "pagination exceeded safety limit")
+}
+
+func TestGithubClient_GetFileContent(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("* @alice\n/terraform @org/sre\n"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/42/contents/.github/CODEOWNERS", r.URL.Path)
+		assert.Equal(t, "main", r.URL.Query().Get("ref"))
+		// GitHub wraps the encoded content across lines.
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": encoded[:10] + "\n" + encoded[10:] + "\n"})
+	}))
+	defer server.Close()
+
+	content, err := newTestGithubClient(server.URL).GetFileContent(context.Background(), 42, "main", ".github/CODEOWNERS")
+	assert.NoError(t, err)
+	assert.Equal(t, "* @alice\n/terraform @org/sre\n", content)
+}
+
+func TestGithubClient_GetLatestCommitTimestamp(t *testing.T) {
+	t.Run("uses the last commit", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/repositories/42/pulls/7/commits", r.URL.Path)
+			_, _ = w.Write([]byte(`[
+				{"sha":"old","commit":{"committer":{"date":"2024-01-01T10:00:00Z"}}},
+				{"sha":"new","commit":{"committer":{"date":"2024-01-01T12:00:00Z"}}}
+			]`))
+		}))
+		defer server.Close()
+
+		timestamp, err := newTestGithubClient(server.URL).GetLatestCommitTimestamp(context.Background(), 42, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), timestamp)
+	})
+
+	t.Run("no commits", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		defer server.Close()
+
+		_, err := newTestGithubClient(server.URL).GetLatestCommitTimestamp(context.Background(), 42, 7)
+		assert.ErrorContains(t, err, "no commits found for PR 7")
+	})
+}
+
+func TestGithubClient_GetLatestPushTimestamp(t *testing.T) {
+	const pr = `{"number":7,"head":{"sha":"new","ref":"feature","repo":{"id":43}}}`
+
+	t.Run("uses the push of the head commit", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repositories/42/pulls/7":
+				_, _ = w.Write([]byte(pr))
+			case "/repositories/43/activity":
+				assert.Equal(t, "refs/heads/feature", r.URL.Query().Get("ref"))
+				_, _ = w.Write([]byte(`[
+					{"after":"new","activity_type":"force_push","timestamp":"2024-01-02T12:00:00Z"},
+					{"after":"old","activity_type":"push","timestamp":"2024-01-01T12:00:00Z"}
+				]`))
+			default:
+				t.Errorf("unexpected request: %s", r.URL.Path)
+			}
+		}))
+		defer server.Close()
+
+		timestamp, err := newTestGithubClient(server.URL).GetLatestPushTimestamp(context.Background(), 42, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), timestamp)
+	})
+
+	t.Run("head commit not found in the activity", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/repositories/42/pulls/7" {
+				_, _ = w.Write([]byte(pr))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"after":"old","timestamp":"2024-01-01T12:00:00Z"}]`))
+		}))
+		defer server.Close()
+
+		_, err := newTestGithubClient(server.URL).GetLatestPushTimestamp(context.Background(), 42, 7)
+		assert.ErrorContains(t, err, "no push of head commit new found in the activity of branch feature")
+	})
+
+	t.Run("activity API unavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/repositories/42/pulls/7" {
+				_, _ = w.Write([]byte(pr))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}))
+		defer server.Close()
+
+		_, err := newTestGithubClient(server.URL).GetLatestPushTimestamp(context.Background(), 42, 7)
+		assert.ErrorIs(t, err, ErrUnsupported)
+	})
+
+	t.Run("deleted head repository", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"number":7,"head":{"sha":"new","ref":"feature","repo":null}}`))
+		}))
+		defer server.Close()
+
+		_, err := newTestGithubClient(server.URL).GetLatestPushTimestamp(context.Background(), 42, 7)
+		assert.ErrorContains(t, err, "head repository of PR 7 no longer exists")
+	})
+}
+
+func TestGithubClient_ListGroupMembers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/orgs/org/teams/sre/members", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"login":"alice"},{"login":"bob"}]`))
+	}))
+	defer server.Close()
+
+	client := newTestGithubClient(server.URL)
+
+	members, err := client.ListGroupMembers(context.Background(), "org/sre")
+	assert.NoError(t, err)
+	assert.Equal(t, []*User{{Username: "alice"}, {Username: "bob"}}, members)
+
+	members, err = client.ListGroupMembers(context.Background(), "org/platform/sre")
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	_, err = client.ListGroupMembers(context.Background(), "alice")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGithubClient_GetCurrentUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/user", r.URL.Path)
+		_, _ = w.Write([]byte(`{"login":"emoji-gate-bot"}`))
+	}))
+	defer server.Close()
+
+	user, err := newTestGithubClient(server.URL).GetCurrentUser(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &User{Username: "emoji-gate-bot"}, user)
+}
+
+func TestGithubClient_Notes(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`[{"id":1,"body":"hello","user":{"login":"alice"}}]`))
+		default:
+			var payload map[string]string
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(githubComment{ID: 2, Body: payload["body"], User: githubUser{Login: "bot"}})
+		}
+	}))
+	defer server.Close()
+
+	client := newTestGithubClient(server.URL)
+
+	notes, err := client.ListNotes(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Note{{ID: 1, Body: "hello", Author: User{Username: "alice"}}}, notes)
+
+	note, err := client.CreateNote(context.Background(), 42, 7, "created")
+	assert.NoError(t, err)
+	assert.Equal(t, "created", note.Body)
+	assert.Equal(t, "bot", note.Author.Username)
+
+	note, err = client.UpdateNote(context.Background(), 42, 7, 2, "updated")
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", note.Body)
+
+	assert.Equal(t, []string{
+		"GET /repositories/42/issues/7/comments",
+		"POST /repositories/42/issues/7/comments",
+		"PATCH /repositories/42/issues/comments/2",
+	}, requests)
+}
+
+func TestGithubClient_GetMergeRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/42/pulls/7", r.URL.Path)
+		_, _ = w.Write([]byte(`{"number":7,"html_url":"https://github.com/org/repo/pull/7","head":{"sha":"abc","ref":"feature"},"base":{"ref":"main"}}`))
+	}))
+	defer server.Close()
+
+	mr, err := newTestGithubClient(server.URL).GetMergeRequest(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, &MergeRequest{
+		IID:          7,
+		SHA:          "abc",
+		SourceBranch: "feature",
+		TargetBranch: "main",
+		WebURL:       "https://github.com/org/repo/pull/7",
+	}, mr)
+}
+
+func TestGithubClient_ListChangedFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/42/pulls/7/files", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"filename":"terraform/main.tf"},{"filename":"modules/vpc/vpc.tf","previous_filename":"modules/old/vpc.tf"}]`))
+	}))
+	defer server.Close()
+
+	paths, err := newTestGithubClient(server.URL).ListChangedFiles(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"terraform/main.tf", "modules/vpc/vpc.tf", "modules/old/vpc.tf"}, paths)
+}
+
+func TestGithubClient_ListApprovals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/42/pulls/7/reviews", r.URL.Path)
+		_, _ = w.Write([]byte(`[
+			{"user":{"login":"alice"},"state":"APPROVED","submitted_at":"2024-01-01T12:00:00Z"},
+			{"user":{"login":"bob"},"state":"APPROVED","submitted_at":"2024-01-01T12:00:00Z"},
+			{"user":{"login":"bob"},"state":"DISMISSED","submitted_at":"2024-01-01T12:00:00Z"}
+		]`))
+	}))
+	defer server.Close()
+
+	approvals, err := newTestGithubClient(server.URL).ListApprovals(context.Background(), 42, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Approval{
+		{User: User{Username: "alice"}, ApprovedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}, approvals)
+}
+
+func TestGithubClient_CommitStatuses(t *testing.T) {
+	var posted map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repositories/42/commits/abc/statuses":
+			_, _ = w.Write([]byte(`[
+				{"context":"emoji-gate/terraform","state":"failure","description":"vetoed"},
+				{"context":"ci/build","state":"success"},
+				{"context":"emoji-gate/terraform","state":"pending"}
+			]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repositories/42/statuses/abc":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestGithubClient(server.URL)
+
+	statuses, err := client.ListCommitStatuses(context.Background(), 42, "abc", "emoji-gate/terraform")
+	assert.NoError(t, err)
+	assert.Equal(t, []*CommitStatus{
+		{Name: "emoji-gate/terraform", State: CommitStatusFailed, Description: "vetoed"},
+		{Name: "emoji-gate/terraform", State: CommitStatusPending},
+	}, statuses)
+
+	err = client.SetCommitStatus(context.Background(), 42, "abc", CommitStatus{Name: "emoji-gate/terraform", State: CommitStatusFailed, Description: "vetoed"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"state": "failure", "context": "emoji-gate/terraform", "description": "vetoed"}, posted)
+
+	err = client.SetCommitStatus(context.Background(), 42, "abc", CommitStatus{State: "running"})
+	assert.ErrorContains(t, err, "unsupported commit status state")
+}
+
+func TestGithubClient_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer server.Close()
+
+	client := newTestGithubClient(server.URL)
+	ctx := context.Background()
+
+	_, err := client.GetProject(ctx, "org/repo")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListAwardEmojis(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.GetFileContent(ctx, 42, "main", "CODEOWNERS")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.GetLatestCommitTimestamp(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListGroupMembers(ctx, "org/sre")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListNotes(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.CreateNote(ctx, 42, 7, "body")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.UpdateNote(ctx, 42, 7, 1, "body")
+	assert.ErrorContains(t, err, "404")
+	_, err = client.GetMergeRequest(ctx, 42, 7)
+	assert.ErrorContains(t, err, "404")
+	_, err = client.ListCommitStatuses(ctx, 42, "abc", "name")
+	assert.ErrorContains(t, err, "404")
+}
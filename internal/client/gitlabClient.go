@@ -5,8 +5,6 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -14,48 +12,28 @@ import (
 	"time"
 )
 
-const (
-	// defaultTimeout is the default HTTP client timeout for API requests.
-	defaultTimeout = 30 * time.Second
-	// maxPerPage is the maximum number of items per page for paginated GitLab API requests.
-	maxPerPage = 100
-)
-
-//go:generate go tool mockgen -destination=mocks/mock_client.go -package=mocks . GitlabClientInterface
-
-// GitlabClientInterface defines the methods that must be implemented by a GitLab client.
-// GetMrCommits is intentionally excluded as it is only used internally by GetLatestCommitTimestamp.
-type GitlabClientInterface interface {
-	GetProject(ctx context.Context, projectPath string) (*Project, error)
-	ListAwardEmojis(ctx context.Context, projectID, mrID int) ([]*AwardEmoji, error)
-	GetFileContent(ctx context.Context, projectID int, branch, filePath string) (string, error)
-	GetLatestCommitTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error)
-}
-
-// GitlabClient implements GitlabClientInterface using the GitLab REST API.
+// GitlabClient implements Client using the GitLab REST API.
 type GitlabClient struct {
-	scheme  string
-	baseURL string
-	token   string
-	client  *http.Client
+	apiURL string
+	auth   Authenticator
+	client *http.Client
 }
 
-// Project represents a GitLab project.
-type Project struct {
-	ID            int    `json:"id"`
-	DefaultBranch string `json:"default_branch"`
+// Group represents a GitLab group or subgroup.
+type Group struct {
+	ID       int    `json:"id"`
+	FullPath string `json:"full_path"`
 }
 
-// AwardEmoji represents an emoji reaction on a GitLab merge request.
-type AwardEmoji struct {
-	Name      string    `json:"name"`
-	User      User      `json:"user"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
+// gitlabDeveloperAccess is the access level of the Developer role, the lowest
+// role whose group members count as code owners.
+const gitlabDeveloperAccess = 30
 
-// User represents a GitLab user.
-type User struct {
-	Username string `json:"username"`
+// gitlabMember represents a member of a GitLab group.
+type gitlabMember struct {
+	Username    string `json:"username"`
+	State       string `json:"state"`
+	AccessLevel int    `json:"access_level"`
 }
 
 // Commit represents a GitLab commit.
@@ -64,48 +42,55 @@ type Commit struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// NewGitlabClient creates a new GitlabClient with the given base URL and token.
-func NewGitlabClient(baseURL, token string) *GitlabClient {
+// NewGitlabClient creates a new GitlabClient with the given base URL and an
+// access token sent in the Private-Token header.
+//
+// The base URL is either a bare hostname as Atlantis provides it (e.g.
+// "gitlab.example.com") or a full URL with a scheme and an optional relative
+// URL root (e.g. "http://corp.example.com/gitlab").
+func NewGitlabClient(baseURL, token string, opts ...Option) *GitlabClient {
+	return NewGitlabClientWithAuth(baseURL, PrivateTokenAuth(StaticToken(token)), opts...)
+}
+
+// NewGitlabClientWithAuth creates a new GitlabClient with the given base URL
+// that authenticates its requests with the given strategy.
+func NewGitlabClientWithAuth(baseURL string, auth Authenticator, opts ...Option) *GitlabClient {
 	return &GitlabClient{
-		scheme:  "https",
-		baseURL: baseURL,
-		token:   token,
-		client:  &http.Client{Timeout: defaultTimeout},
+		apiURL: gitlabAPIURL(baseURL),
+		auth:   auth,
+		client: newHTTPClient(opts),
 	}
 }
 
-// doGet performs an HTTP GET request with context and returns the response body and headers.
-func (g *GitlabClient) doGet(ctx context.Context, path string) ([]byte, http.Header, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s://%s/api/v4/%s", g.scheme, g.baseURL, path), nil)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+// gitlabAPIURL normalizes a GitLab base URL into the root of its v4 API.
+// HTTPS is assumed when no scheme is given, and a URL that already points at
+// the API root is kept as is.
+func gitlabAPIURL(baseURL string) string {
+	apiURL := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if !strings.Contains(apiURL, "://") {
+		apiURL = "https://" + apiURL
 	}
-	req.Header.Add("Private-Token", g.token)
-
-	resp, err := g.client.Do(req)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to execute request: %w", err)
+	if !strings.HasSuffix(apiURL, "/api/v4") {
+		apiURL += "/api/v4"
 	}
-	defer func(Body io.ReadCloser) {
-		if err := Body.Close(); err != nil {
-			slog.Warn("Failed to close response body", "error", err)
-		}
-	}(resp.Body)
+	return apiURL
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, nil, fmt.Errorf("received non-200 response: %d with failed body read: %w", resp.StatusCode, err)
-		}
-		return nil, nil, fmt.Errorf("received non-200 response: %d - %s", resp.StatusCode, string(body))
-	}
+// doGet performs an HTTP GET request with context and returns the response body and headers.
+// Transient failures are retried by the transport according to the client's RetryPolicy.
+func (g *GitlabClient) doGet(ctx context.Context, path string) ([]byte, http.Header, error) {
+	return g.do(ctx, http.MethodGet, path, nil)
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+// do performs an authenticated request against the GitLab API, sending the
+// payload as a JSON body when it is not nil.
+func (g *GitlabClient) do(ctx context.Context, method, path string, payload any) ([]byte, http.Header, error) {
+	requestURL := fmt.Sprintf("%s/%s", g.apiURL, path)
+	headers := make(http.Header)
+	if err := g.auth.Authenticate(headers); err != nil {
+		return nil, nil, fmt.Errorf("failed to authenticate request: %w", err)
 	}
-
-	return body, resp.Header, nil
+	return doRequest(ctx, g.client, method, requestURL, headers, payload)
 }
 
 // get sends a GET request to the specified path and decodes the response into the target.
@@ -114,12 +99,16 @@ func (g *GitlabClient) get(ctx context.Context, path string, target any) error {
 	if err != nil {
 		return err
 	}
+	return decodeJSON(body, target)
+}
 
-	if err := json.Unmarshal(body, target); err != nil {
-		return fmt.Errorf("failed to unmarshal response: %w", err)
+// send performs a request with a JSON payload and decodes the response into the target.
+func (g *GitlabClient) send(ctx context.Context, method, path string, payload, target any) error {
+	body, _, err := g.do(ctx, method, path, payload)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return decodeJSON(body, target)
 }
 
 // getAll sends paginated GET requests and collects all array results across pages.
@@ -127,8 +116,6 @@ func (g *GitlabClient) get(ctx context.Context, path string, target any) error {
 // The next page number is read from GitLab's X-Next-Page response header.
 // A safety limit prevents unbounded loops in case of API misbehavior.
 func getAll[T any](ctx context.Context, g *GitlabClient, basePath string) ([]T, error) {
-	const maxPages = 100 // Safety limit: 100 pages * 100 items = 10,000 items max
-
 	var all []T
 	separator := "?"
 	if strings.Contains(basePath, "?") {
@@ -176,6 +163,19 @@ func (g *GitlabClient) ListAwardEmojis(ctx context.Context, projectID, mrID int)
 	return getAll[*AwardEmoji](ctx, g, path)
 }
 
+// ListApprovals lists the users who approved the specified merge request with
+// GitLab's "Approve" button. GitLab versions that do not report when a user
+// approved leave ApprovedAt zero.
+func (g *GitlabClient) ListApprovals(ctx context.Context, projectID, mrID int) ([]*Approval, error) {
+	var state struct {
+		ApprovedBy []*Approval `json:"approved_by"`
+	}
+	if err := g.get(ctx, fmt.Sprintf("projects/%d/merge_requests/%d/approvals", projectID, mrID), &state); err != nil {
+		return nil, err
+	}
+	return state.ApprovedBy, nil
+}
+
 // GetFileContent retrieves the content of the specified file in the given project and branch.
 func (g *GitlabClient) GetFileContent(ctx context.Context, projectID int, branch, filePath string) (string, error) {
 	encodedPath := url.PathEscape(filePath)
@@ -208,3 +208,162 @@ func (g *GitlabClient) GetLatestCommitTimestamp(ctx context.Context, projectID,
 	}
 	return commits[0].CreatedAt, nil
 }
+
+// GetLatestPushTimestamp retrieves when the current head commit of the
+// specified merge request was pushed. GitLab records a merge request version
+// for every push, including rebases and force-pushes, so the newest version
+// whose head is the merge request's current head tells when it was pushed,
+// regardless of the dates recorded in the commit itself.
+func (g *GitlabClient) GetLatestPushTimestamp(ctx context.Context, projectID, mrID int) (time.Time, error) {
+	mr, err := g.GetMergeRequest(ctx, projectID, mrID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	versions, err := getAll[struct {
+		HeadCommitSHA string    `json:"head_commit_sha"`
+		CreatedAt     time.Time `json:"created_at"`
+	}](ctx, g, fmt.Sprintf("projects/%d/merge_requests/%d/versions", projectID, mrID))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Versions are listed newest first.
+	for _, version := range versions {
+		if version.HeadCommitSHA == mr.SHA {
+			return version.CreatedAt, nil
+		}
+	}
+	// GitLab creates versions asynchronously, so the head may have been pushed
+	// moments ago. Failing is safer than trusting an older version.
+	return time.Time{}, fmt.Errorf("no version of MR %d has head commit %s yet", mrID, mr.SHA)
+}
+
+// ListChangedFiles lists the paths of the files changed by the specified merge
+// request. Renamed and deleted files are listed with their old path as well.
+func (g *GitlabClient) ListChangedFiles(ctx context.Context, projectID, mrID int) ([]string, error) {
+	diffs, err := getAll[struct {
+		OldPath string `json:"old_path"`
+		NewPath string `json:"new_path"`
+	}](ctx, g, fmt.Sprintf("projects/%d/merge_requests/%d/diffs", projectID, mrID))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(diffs))
+	for _, diff := range diffs {
+		paths = append(paths, diff.NewPath, diff.OldPath)
+	}
+	return uniquePaths(paths), nil
+}
+
+// ListGroupMembers lists the members of the given group, including members
+// inherited from ancestor groups and the direct members of every nested subgroup.
+// Only active members with the Developer role or higher are returned, as GitLab
+// requires of code owners, so guests, reporters and blocked users cannot approve.
+// Each user is returned once, even if they belong to several of these groups.
+// Owners that are not groups, such as users, are reported as ErrNotFound.
+func (g *GitlabClient) ListGroupMembers(ctx context.Context, groupPath string) ([]*User, error) {
+	escapedPath := url.PathEscape(groupPath)
+
+	members, err := getAll[*gitlabMember](ctx, g, fmt.Sprintf("groups/%s/members/all", escapedPath))
+	if err != nil {
+		return nil, err
+	}
+
+	subgroups, err := getAll[*Group](ctx, g, fmt.Sprintf("groups/%s/descendant_groups", escapedPath))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subgroup := range subgroups {
+		subgroupMembers, err := getAll[*gitlabMember](ctx, g, fmt.Sprintf("groups/%d/members", subgroup.ID))
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, subgroupMembers...)
+	}
+
+	seen := make(map[string]struct{}, len(members))
+	unique := make([]*User, 0, len(members))
+	for _, member := range members {
+		if member.State != "active" || member.AccessLevel < gitlabDeveloperAccess {
+			continue
+		}
+		if _, ok := seen[member.Username]; ok {
+			continue
+		}
+		seen[member.Username] = struct{}{}
+		unique = append(unique, &User{Username: member.Username})
+	}
+
+	return unique, nil
+}
+
+// GetCurrentUser retrieves the user the token belongs to.
+func (g *GitlabClient) GetCurrentUser(ctx context.Context) (*User, error) {
+	var user User
+	if err := g.get(ctx, "user", &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListNotes lists all notes on the specified merge request, oldest first.
+// Results are paginated to ensure all notes are retrieved.
+func (g *GitlabClient) ListNotes(ctx context.Context, projectID, mrID int) ([]*Note, error) {
+	path := fmt.Sprintf("projects/%d/merge_requests/%d/notes?sort=asc&order_by=created_at", projectID, mrID)
+	return getAll[*Note](ctx, g, path)
+}
+
+// CreateNote adds a note with the given Markdown body to the specified merge request.
+func (g *GitlabClient) CreateNote(ctx context.Context, projectID, mrID int, body string) (*Note, error) {
+	path := fmt.Sprintf("projects/%d/merge_requests/%d/notes", projectID, mrID)
+	var note Note
+	if err := g.send(ctx, http.MethodPost, path, map[string]string{"body": body}, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// UpdateNote replaces the body of an existing note on the specified merge request.
+func (g *GitlabClient) UpdateNote(ctx context.Context, projectID, mrID, noteID int, body string) (*Note, error) {
+	path := fmt.Sprintf("projects/%d/merge_requests/%d/notes/%d", projectID, mrID, noteID)
+	var note Note
+	if err := g.send(ctx, http.MethodPut, path, map[string]string{"body": body}, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetMergeRequest retrieves the details of the specified merge request,
+// including the SHA of its head commit.
+func (g *GitlabClient) GetMergeRequest(ctx context.Context, projectID, mrID int) (*MergeRequest, error) {
+	var mr MergeRequest
+	if err := g.get(ctx, fmt.Sprintf("projects/%d/merge_requests/%d", projectID, mrID), &mr); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// ListCommitStatuses lists the statuses with the given name attached to a
+// commit, most recent first. GitLab sorts them oldest first unless asked
+// otherwise.
+func (g *GitlabClient) ListCommitStatuses(ctx context.Context, projectID int, sha, name string) ([]*CommitStatus, error) {
+	path := fmt.Sprintf("projects/%d/repository/commits/%s/statuses?name=%s&order_by=id&sort=desc", projectID, url.PathEscape(sha), url.QueryEscape(name))
+	return getAll[*CommitStatus](ctx, g, path)
+}
+
+// SetCommitStatus creates or updates the status with the given name on a commit.
+func (g *GitlabClient) SetCommitStatus(ctx context.Context, projectID int, sha string, status CommitStatus) error {
+	payload := map[string]string{
+		"state":       status.State,
+		"name":        status.Name,
+		"description": status.Description,
+	}
+	if status.TargetURL != "" {
+		payload["target_url"] = status.TargetURL
+	}
+	var created CommitStatus
+	return g.send(ctx, http.MethodPost, fmt.Sprintf("projects/%d/statuses/%s", projectID, url.PathEscape(sha)), payload, &created)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -125,10 +126,7 @@ func mockGitLabServer() *httptest.Server {
 
 // newTestGitlabClient creates a test GitLab client pointing to the mock server.
 func newTestGitlabClient(baseURL string) *GitlabClient {
-	trimmed := strings.TrimPrefix(baseURL, "http://")
-	client := NewGitlabClient(trimmed, "dummyToken")
-	client.scheme = "http"
-	return client
+	return NewGitlabClient(baseURL, "dummyToken")
 }
 
 // ------------------ Actual Tests ------------------
@@ -161,9 +159,45 @@ func TestGitlabClient_SuccessCases(t *testing.T) {
 	})
 }
 
+func TestGitlabAPIURL(t *testing.T) {
+	testCases := []struct {
+		baseURL string
+		want    string
+	}{
+		{"gitlab.com", "https://gitlab.com/api/v4"},
+		{"gitlab.example.com:8443", "https://gitlab.example.com:8443/api/v4"},
+		{"https://gitlab.example.com", "https://gitlab.example.com/api/v4"},
+		{"https://gitlab.example.com/", "https://gitlab.example.com/api/v4"},
+		{"http://localhost:8080", "http://localhost:8080/api/v4"},
+		{"https://corp.example.com/gitlab", "https://corp.example.com/gitlab/api/v4"},
+		{"corp.example.com/gitlab/", "https://corp.example.com/gitlab/api/v4"},
+		{"https://corp.example.com/gitlab/api/v4/", "https://corp.example.com/gitlab/api/v4"},
+		{" gitlab.example.com ", "https://gitlab.example.com/api/v4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.baseURL, func(t *testing.T) {
+			assert.Equal(t, tc.want, gitlabAPIURL(tc.baseURL))
+		})
+	}
+}
+
+func TestGitlabClient_RelativeURLRoot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gitlab/api/v4/projects/group%2Fproject", r.URL.EscapedPath())
+		_, _ = w.Write([]byte(`{"id":1,"default_branch":"main"}`))
+	}))
+	defer server.Close()
+
+	client := NewGitlabClient(server.URL+"/gitlab/", "dummyToken")
+	project, err := client.GetProject(context.Background(), "group/project")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, project.ID)
+}
+
 func TestGitlabClient_Timeout(t *testing.T) {
 	client := NewGitlabClient("example.com", "token")
-	assert.Equal(t, defaultTimeout, client.client.Timeout)
+	assert.Equal(t, defaultTimeout+DefaultRetryPolicy().Budget, client.client.Timeout)
 }
 
 func TestGitlabClient_GetFileContent_URLEncoding(t *testing.T) {
@@ -355,7 +389,6 @@ func TestGitlabClient_Get_ErrorCases(t *testing.T) {
 
 	t.Run("failed to create request", func(t *testing.T) {
 		invalidClient := NewGitlabClient("%41:8080", "dummyToken")
-		invalidClient.scheme = "http"
 
 		var target any
 		err := invalidClient.get(context.Background(), "test-path", &target)
@@ -365,7 +398,6 @@ func TestGitlabClient_Get_ErrorCases(t *testing.T) {
 
 	t.Run("failed to execute request - mocked error", func(t *testing.T) {
 		clientWithMockErr := NewGitlabClient("valid-url", "dummyToken")
-		clientWithMockErr.scheme = "http"
 		clientWithMockErr.client = &http.Client{
 			Transport: &MockRoundTripper{
 				RoundTripFunc: func(req *http.Request) (*http.Response, error) {
@@ -381,7 +413,7 @@ func TestGitlabClient_Get_ErrorCases(t *testing.T) {
 	})
 
 	t.Run("HTTP request failure (invalid URL)", func(t *testing.T) {
-		clientInvalidURL := NewGitlabClient("invalid-url", "dummyToken")
+		clientInvalidURL := NewGitlabClient("invalid-url", "dummyToken", WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
 
 		var target any
 		err := clientInvalidURL.get(context.Background(), "test-path", &target)
@@ -405,7 +437,6 @@ func TestGitlabClient_Get_ErrorCases(t *testing.T) {
 
 	t.Run("failed to read response body (200 OK)", func(t *testing.T) {
 		readFailClient := NewGitlabClient("valid-url", "dummyToken")
-		readFailClient.scheme = "http"
 		readFailClient.client = &http.Client{
 			Transport: &MockRoundTripper{
 				RoundTripFunc: func(req *http.Request) (*http.Response, error) {
@@ -425,7 +456,6 @@ func TestGitlabClient_Get_ErrorCases(t *testing.T) {
 
 	t.Run("failed to read body (non-200)", func(t *testing.T) {
 		readFailClient := NewGitlabClient("valid-url", "dummyToken")
-		readFailClient.scheme = "http"
 		readFailClient.client = &http.Client{
 			Transport: &MockRoundTripper{
 				RoundTripFunc: func(req *http.Request) (*http.Response, error) {
@@ -464,7 +494,6 @@ func TestGitlabClient_GetFileContent_ErrorCases(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			client := NewGitlabClient("valid-url", "dummyToken")
-			client.scheme = "http"
 			client.client = &http.Client{
 				Transport: newMockTransport(tc.status, tc.body, tc.transportErr),
 			}
@@ -477,12 +506,35 @@ func TestGitlabClient_GetFileContent_ErrorCases(t *testing.T) {
 	}
 }
 
+// Tests that missing resources can be told apart from other failures.
+func TestGitlabClient_NotFound(t *testing.T) {
+	testCases := []struct {
+		name         string
+		status       int
+		wantNotFound bool
+	}{
+		{"Not found", http.StatusNotFound, true},
+		{"Forbidden", http.StatusForbidden, false},
+		{"Server error", http.StatusInternalServerError, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewGitlabClient("valid-url", "dummyToken")
+			client.client = &http.Client{Transport: newMockTransport(tc.status, `{"message":"404 File Not Found"}`, nil)}
+
+			_, err := client.GetFileContent(context.Background(), 1, "main", "CODEOWNERS")
+			assert.Error(t, err)
+			assert.Equal(t, tc.wantNotFound, errors.Is(err, ErrNotFound))
+		})
+	}
+}
+
 // Tests for invalid Base64 content in GetFileContent.
 func TestGitlabClient_GetFileContent_Base64DecodeFailure(t *testing.T) {
 	client := &GitlabClient{
-		scheme:  "https",
-		baseURL: "example.com",
-		token:   "test-token",
+		apiURL: "https://example.com/api/v4",
+		auth:   PrivateTokenAuth(StaticToken("test-token")),
 		client: &http.Client{
 			Transport: newMockTransport(http.StatusOK, `{"content":"!!invalid_base64!!"}`, nil),
 		},
@@ -583,7 +635,6 @@ func TestGitlabClient_Get_BodyCloseErrorLogging(t *testing.T) {
 	defer slog.SetDefault(original)
 
 	client := NewGitlabClient("valid-url", "dummyToken")
-	client.scheme = "http"
 	client.client = &http.Client{Transport: mockTransport}
 
 	var target map[string]any
@@ -593,3 +644,352 @@ func TestGitlabClient_Get_BodyCloseErrorLogging(t *testing.T) {
 	assert.Contains(t, logBuf.String(), "Failed to close response body", "Log should contain the close failure message")
 	assert.Contains(t, logBuf.String(), "mocked close error", "Log should include the specific close error")
 }
+
+// Tests for ListGroupMembers.
+func TestGitlabClient_ListGroupMembers(t *testing.T) {
+	t.Run("collects inherited and subgroup members without duplicates", func(t *testing.T) {
+		var requested []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rawPath := r.URL.RawPath
+			if rawPath == "" {
+				rawPath = r.URL.Path
+			}
+			requested = append(requested, rawPath)
+
+			var response any
+			switch rawPath {
+			case "/api/v4/groups/platform%2Fsre/members/all":
+				response = []*gitlabMember{
+					{Username: "alice", State: "active", AccessLevel: 30},
+					{Username: "org-owner", State: "active", AccessLevel: 50},
+				}
+			case "/api/v4/groups/platform%2Fsre/descendant_groups":
+				response = []*Group{{ID: 7, FullPath: "platform/sre/oncall"}}
+			case "/api/v4/groups/7/members":
+				response = []*gitlabMember{
+					{Username: "bob", State: "active", AccessLevel: 40},
+					{Username: "alice", State: "active", AccessLevel: 30},
+				}
+			default:
+				t.Errorf("unexpected request: %s", rawPath)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(response)
+		}))
+		defer server.Close()
+
+		client := newTestGitlabClient(server.URL)
+		members, err := client.ListGroupMembers(context.Background(), "platform/sre")
+		assert.NoError(t, err)
+
+		var usernames []string
+		for _, member := range members {
+			usernames = append(usernames, member.Username)
+		}
+		assert.Equal(t, []string{"alice", "org-owner", "bob"}, usernames)
+		assert.Equal(t, []string{
+			"/api/v4/groups/platform%2Fsre/members/all",
+			"/api/v4/groups/platform%2Fsre/descendant_groups",
+			"/api/v4/groups/7/members",
+		}, requested)
+	})
+
+	t.Run("skips members below Developer and inactive members", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/v4/groups/team/members/all":
+				_, _ = w.Write([]byte(`[
+					{"username":"guest","state":"active","access_level":10},
+					{"username":"reporter","state":"active","access_level":20},
+					{"username":"developer","state":"active","access_level":30},
+					{"username":"blocked","state":"blocked","access_level":50},
+					{"username":"pending","state":"awaiting","access_level":40}
+				]`))
+			case "/api/v4/groups/team/descendant_groups":
+				_, _ = w.Write([]byte(`[{"id":3,"full_path":"team/sub"}]`))
+			case "/api/v4/groups/3/members":
+				_, _ = w.Write([]byte(`[
+					{"username":"maintainer","state":"active","access_level":40},
+					{"username":"sub-reporter","state":"active","access_level":20}
+				]`))
+			default:
+				t.Errorf("unexpected request: %s", r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		members, err := newTestGitlabClient(server.URL).ListGroupMembers(context.Background(), "team")
+		assert.NoError(t, err)
+		assert.Equal(t, []*User{{Username: "developer"}, {Username: "maintainer"}}, members)
+	})
+
+	testCases := []struct {
+		name     string
+		failPath string
+	}{
+		{"members request fails", "/api/v4/groups/team/members/all"},
+		{"descendant groups request fails", "/api/v4/groups/team/descendant_groups"},
+		{"subgroup members request fails", "/api/v4/groups/3/members"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == tc.failPath {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte("403 Forbidden"))
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "descendant_groups") {
+					_, _ = w.Write([]byte(`[{"id":3,"full_path":"team/sub"}]`))
+					return
+				}
+				_, _ = w.Write([]byte(`[{"username":"alice"}]`))
+			}))
+			defer server.Close()
+
+			client := newTestGitlabClient(server.URL)
+			members, err := client.ListGroupMembers(context.Background(), "team")
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "403")
+			assert.Nil(t, members)
+		})
+	}
+}
+
+func TestGitlabClient_ListNotes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/projects/1/merge_requests/2/notes", r.URL.Path)
+		assert.Equal(t, "asc", r.URL.Query().Get("sort"))
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("X-Next-Page", "2")
+			_, _ = w.Write([]byte(`[{"id":1,"body":"first","author":{"username":"alice"}}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":2,"body":"second","author":{"username":"bot"},"system":true}]`))
+	}))
+	defer server.Close()
+
+	client := newTestGitlabClient(server.URL)
+	notes, err := client.ListNotes(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, notes, 2)
+	assert.Equal(t, "alice", notes[0].Author.Username)
+	assert.True(t, notes[1].System)
+}
+
+func TestGitlabClient_CreateAndUpdateNote(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "dummyToken", r.Header.Get("Private-Token"))
+
+		var payload map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			status = http.StatusCreated
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(Note{ID: 9, Body: payload["body"]})
+	}))
+	defer server.Close()
+
+	client := newTestGitlabClient(server.URL)
+
+	created, err := client.CreateNote(context.Background(), 1, 2, "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, 9, created.ID)
+	assert.Equal(t, "hello", created.Body)
+
+	updated, err := client.UpdateNote(context.Background(), 1, 2, 9, "updated")
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", updated.Body)
+
+	assert.Equal(t, []string{
+		"POST /api/v4/projects/1/merge_requests/2/notes",
+		"PUT /api/v4/projects/1/merge_requests/2/notes/9",
+	}, requests)
+
+	t.Run("errors are returned", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("403 Forbidden"))
+		}))
+		defer failing.Close()
+
+		client := newTestGitlabClient(failing.URL)
+		_, err := client.CreateNote(context.Background(), 1, 2, "hello")
+		assert.ErrorContains(t, err, "403")
+		_, err = client.UpdateNote(context.Background(), 1, 2, 9, "hello")
+		assert.ErrorContains(t, err, "403")
+	})
+}
+
+func TestGitlabClient_GetCurrentUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/user", r.URL.Path)
+		_, _ = w.Write([]byte(`{"id":1,"username":"emoji-gate-bot"}`))
+	}))
+	defer server.Close()
+
+	user, err := newTestGitlabClient(server.URL).GetCurrentUser(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &User{Username: "emoji-gate-bot"}, user)
+}
+
+func TestGitlabClient_GetMergeRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/1/merge_requests/2" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"iid":2,"sha":"abc123","source_branch":"feature","target_branch":"main","web_url":"https://gitlab.example.com/org/repo/-/merge_requests/2"}`))
+	}))
+	defer server.Close()
+
+	client := newTestGitlabClient(server.URL)
+
+	mr, err := client.GetMergeRequest(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &MergeRequest{
+		IID:          2,
+		SHA:          "abc123",
+		SourceBranch: "feature",
+		TargetBranch: "main",
+		WebURL:       "https://gitlab.example.com/org/repo/-/merge_requests/2",
+	}, mr)
+
+	mr, err = client.GetMergeRequest(context.Background(), 1, 3)
+	assert.ErrorContains(t, err, "404")
+	assert.Nil(t, mr)
+}
+
+func TestGitlabClient_GetLatestPushTimestamp(t *testing.T) {
+	// newServer serves a merge request whose head is "new" along with the
+	// given versions, newest first.
+	newServer := func(versions string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v4/projects/1/merge_requests/2":
+				_, _ = w.Write([]byte(`{"iid":2,"sha":"new"}`))
+			case "/api/v4/projects/1/merge_requests/2/versions":
+				_, _ = w.Write([]byte(versions))
+			default:
+				t.Errorf("unexpected request: %s", r.URL.Path)
+			}
+		}))
+	}
+
+	t.Run("uses the version of the head commit", func(t *testing.T) {
+		server := newServer(`[
+			{"id":2,"head_commit_sha":"new","created_at":"2024-01-02T12:00:00Z"},
+			{"id":1,"head_commit_sha":"old","created_at":"2024-01-01T12:00:00Z"}
+		]`)
+		defer server.Close()
+
+		timestamp, err := newTestGitlabClient(server.URL).GetLatestPushTimestamp(context.Background(), 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), timestamp)
+	})
+
+	t.Run("uses the latest push of a head commit pushed again after a rebase", func(t *testing.T) {
+		// The branch was rebased onto "other" and then force-pushed back to "new".
+		server := newServer(`[
+			{"id":3,"head_commit_sha":"new","created_at":"2024-01-03T12:00:00Z"},
+			{"id":2,"head_commit_sha":"other","created_at":"2024-01-02T12:00:00Z"},
+			{"id":1,"head_commit_sha":"new","created_at":"2024-01-01T12:00:00Z"}
+		]`)
+		defer server.Close()
+
+		timestamp, err := newTestGitlabClient(server.URL).GetLatestPushTimestamp(context.Background(), 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC), timestamp)
+	})
+
+	t.Run("head commit without a version yet", func(t *testing.T) {
+		server := newServer(`[{"id":1,"head_commit_sha":"old","created_at":"2024-01-01T12:00:00Z"}]`)
+		defer server.Close()
+
+		_, err := newTestGitlabClient(server.URL).GetLatestPushTimestamp(context.Background(), 1, 2)
+		assert.ErrorContains(t, err, "no version of MR 2 has head commit new yet")
+	})
+}
+
+func TestGitlabClient_ListChangedFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/projects/1/merge_requests/2/diffs", r.URL.Path)
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("X-Next-Page", "2")
+			_, _ = w.Write([]byte(`[{"old_path":"terraform/main.tf","new_path":"terraform/main.tf"},{"old_path":"modules/old/vpc.tf","new_path":"modules/vpc/vpc.tf"}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"old_path":"README.md","new_path":"README.md","deleted_file":true}]`))
+	}))
+	defer server.Close()
+
+	paths, err := newTestGitlabClient(server.URL).ListChangedFiles(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"terraform/main.tf", "modules/vpc/vpc.tf", "modules/old/vpc.tf", "README.md"}, paths)
+}
+
+func TestGitlabClient_ListApprovals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/projects/1/merge_requests/2/approvals", r.URL.Path)
+		_, _ = w.Write([]byte(`{"approved":true,"approved_by":[
+			{"user":{"username":"alice"},"approved_at":"2024-01-01T12:00:00Z"},
+			{"user":{"username":"bob"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	approvals, err := newTestGitlabClient(server.URL).ListApprovals(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Approval{
+		{User: User{Username: "alice"}, ApprovedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{User: User{Username: "bob"}},
+	}, approvals)
+}
+
+func TestGitlabClient_CommitStatuses(t *testing.T) {
+	var posted map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/1/repository/commits/abc123/statuses":
+			assert.Equal(t, "emoji-gate/terraform", r.URL.Query().Get("name"))
+			assert.Equal(t, "id", r.URL.Query().Get("order_by"))
+			assert.Equal(t, "desc", r.URL.Query().Get("sort"))
+			_, _ = w.Write([]byte(`[{"name":"emoji-gate/terraform","status":"pending","description":"waiting"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v4/projects/1/statuses/abc123":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"name":"emoji-gate/terraform","status":"success"}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestGitlabClient(server.URL)
+
+	statuses, err := client.ListCommitStatuses(context.Background(), 1, "abc123", "emoji-gate/terraform")
+	assert.NoError(t, err)
+	assert.Equal(t, []*CommitStatus{{Name: "emoji-gate/terraform", State: CommitStatusPending, Description: "waiting"}}, statuses)
+
+	err = client.SetCommitStatus(context.Background(), 1, "abc123", CommitStatus{
+		Name:        "emoji-gate/terraform",
+		State:       CommitStatusSuccess,
+		Description: "approved",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "emoji-gate/terraform", "state": "success", "description": "approved"}, posted)
+}
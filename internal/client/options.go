@@ -0,0 +1,44 @@
+package client
+
+import (
+	"net/http"
+)
+
+// Option configures the HTTP client used by a provider client.
+type Option func(*clientOptions)
+
+// clientOptions holds the settings collected from the options.
+type clientOptions struct {
+	retry     RetryPolicy
+	transport http.RoundTripper
+}
+
+// WithRetryPolicy sets the policy used to retry idempotent requests after
+// transient failures. DefaultRetryPolicy is used when the option is omitted.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *clientOptions) {
+		o.retry = policy
+	}
+}
+
+// WithTransport sets the transport requests are sent through, e.g. one built
+// by NewTransport. http.DefaultTransport is used when the option is omitted.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// newHTTPClient builds the HTTP client shared by every provider client from
+// the given options. The timeout covers a whole call, so it is extended by the
+// retry budget to leave room for the waits between attempts.
+func newHTTPClient(opts []Option) *http.Client {
+	o := clientOptions{retry: DefaultRetryPolicy(), transport: http.DefaultTransport}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &http.Client{
+		Timeout:   defaultTimeout + o.retry.Budget,
+		Transport: newRetryTransport(o.transport, o.retry),
+	}
+}
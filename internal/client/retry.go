@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unixTimestampThreshold separates the two forms of RateLimit-Reset: GitLab
+// sends the Unix time at which the limit resets, while the IETF draft sends
+// the number of seconds until then. No sane delay is anywhere near 2001.
+const unixTimestampThreshold = 1_000_000_000
+
+// RetryPolicy controls how idempotent requests are retried after transport
+// errors and transient responses (429, 502, 503 and 504).
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first one; 1 disables retries
+	Budget      time.Duration // Maximum total time spent waiting between attempts
+	BaseDelay   time.Duration // Delay before the first retry, doubled for every further one
+	MaxDelay    time.Duration // Upper bound of a single computed delay
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Budget:      20 * time.Second,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// retryTransport is an http.RoundTripper that retries idempotent requests
+// according to a RetryPolicy. Delays grow exponentially with jitter unless the
+// server asks for a specific delay through Retry-After or RateLimit-Reset.
+// A retry is only attempted when its delay fits both the remaining budget and
+// the request's context deadline; otherwise the last response is returned.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(d time.Duration) time.Duration
+}
+
+// newRetryTransport wraps the transport with the retry policy.
+func newRetryTransport(next http.RoundTripper, policy RetryPolicy) *retryTransport {
+	return &retryTransport{
+		next:   next,
+		policy: policy,
+		now:    time.Now,
+		sleep:  sleepContext,
+		jitter: equalJitter,
+	}
+}
+
+// RoundTrip sends the request, retrying it while the failure is transient and
+// the policy allows another attempt.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isRetryableRequest(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.policy.MaxAttempts || ctx.Err() != nil || !isTransient(resp, err) {
+			return resp, err
+		}
+
+		delay, hinted := serverDelay(resp, t.now())
+		if !hinted {
+			delay = t.backoff(attempt)
+		}
+		if waited+delay > t.policy.Budget || !fitsDeadline(ctx, t.now(), delay) {
+			slog.Warn("Giving up retrying request, the next attempt would exceed the retry budget or deadline",
+				"method", req.Method, "url", req.URL.Redacted(), "attempt", attempt, "delay", delay)
+			return resp, err
+		}
+
+		attrs := []any{"method", req.Method, "url", req.URL.Redacted(), "attempt", attempt, "delay", delay}
+		if err != nil {
+			attrs = append(attrs, "error", err)
+		} else {
+			attrs = append(attrs, "status", resp.StatusCode)
+			discard(resp)
+		}
+		slog.Warn("Retrying request after a transient failure", attrs...)
+
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		waited += delay
+	}
+}
+
+// backoff returns the jittered exponential delay before the given retry.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	delay := t.policy.BaseDelay
+	for i := 1; i < attempt && delay < t.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return t.jitter(min(delay, t.policy.MaxDelay))
+}
+
+// isRetryableRequest reports whether the request is idempotent and can be
+// sent again as is, which holds for GET and HEAD requests without a body.
+func isRetryableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// isTransient reports whether a failed attempt is worth retrying.
+func isTransient(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// serverDelay returns the delay the server asked for, read from Retry-After
+// (seconds or an HTTP date) or, for rate-limited responses, RateLimit-Reset.
+func serverDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if value := resp.Header.Get("RateLimit-Reset"); value != "" {
+			if reset, err := strconv.ParseInt(value, 10, 64); err == nil {
+				if reset >= unixTimestampThreshold {
+					return max(time.Unix(reset, 0).Sub(now), 0), true
+				}
+				return max(time.Duration(reset)*time.Second, 0), true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// fitsDeadline reports whether waiting for the delay still leaves the
+// context's deadline in the future.
+func fitsDeadline(ctx context.Context, now time.Time, delay time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || now.Add(delay).Before(deadline)
+}
+
+// equalJitter returns a random delay between half the given delay and the
+// full delay, so concurrent clients do not retry in lockstep.
+func equalJitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half+1)
+}
+
+// sleepContext waits for the delay or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// discard drains and closes a response that is replaced by a retry, so its
+// connection can be reused.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if err := resp.Body.Close(); err != nil {
+		slog.Warn("Failed to close response body", "error", err)
+	}
+}
@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRetryPolicy is a policy with round numbers that keeps delays predictable.
+var testRetryPolicy = RetryPolicy{MaxAttempts: 4, Budget: time.Minute, BaseDelay: time.Second, MaxDelay: 3 * time.Second}
+
+// scriptedTransport answers each attempt with the next scripted response,
+// repeating the last one once the script runs out.
+func scriptedTransport(responses ...func() (*http.Response, error)) (http.RoundTripper, *int) {
+	attempts := 0
+	return &MockRoundTripper{
+		RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+			i := min(attempts, len(responses)-1)
+			attempts++
+			return responses[i]()
+		},
+	}, &attempts
+}
+
+// respond returns a scripted response with the status and headers.
+func respond(status int, headers map[string]string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		resp := &http.Response{StatusCode: status, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("body"))}
+		for name, value := range headers {
+			resp.Header.Set(name, value)
+		}
+		return resp, nil
+	}
+}
+
+// newTestRetryTransport wraps the transport without jitter, recording the
+// delays instead of sleeping.
+func newTestRetryTransport(next http.RoundTripper, policy RetryPolicy, now time.Time) (*retryTransport, *[]time.Duration) {
+	var delays []time.Duration
+	transport := newRetryTransport(next, policy)
+	transport.now = func() time.Time { return now }
+	transport.jitter = func(d time.Duration) time.Duration { return d }
+	transport.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return transport, &delays
+}
+
+func TestRetryTransport(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	transportErr := func() (*http.Response, error) { return nil, errors.New("connection reset") }
+
+	testCases := []struct {
+		name         string
+		method       string
+		policy       RetryPolicy
+		responses    []func() (*http.Response, error)
+		wantStatus   int
+		wantErr      string
+		wantAttempts int
+		wantDelays   []time.Duration
+	}{
+		{
+			name:         "Exponential backoff until success",
+			responses:    []func() (*http.Response, error){respond(502, nil), respond(504, nil), respond(200, nil)},
+			wantStatus:   200,
+			wantAttempts: 3,
+			wantDelays:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:         "Backoff is capped by the maximum delay",
+			responses:    []func() (*http.Response, error){respond(503, nil)},
+			wantStatus:   503,
+			wantAttempts: 4,
+			wantDelays:   []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:         "Transport errors are retried",
+			responses:    []func() (*http.Response, error){transportErr, respond(200, nil)},
+			wantStatus:   200,
+			wantAttempts: 2,
+			wantDelays:   []time.Duration{time.Second},
+		},
+		{
+			name:         "Last transport error is returned",
+			policy:       RetryPolicy{MaxAttempts: 2, Budget: time.Minute, BaseDelay: time.Second, MaxDelay: time.Second},
+			responses:    []func() (*http.Response, error){transportErr},
+			wantErr:      "connection reset",
+			wantAttempts: 2,
+			wantDelays:   []time.Duration{time.Second},
+		},
+		{
+			name:         "Retry-After in seconds",
+			responses:    []func() (*http.Response, error){respond(429, map[string]string{"Retry-After": "7"}), respond(200, nil)},
+			wantStatus:   200,
+			wantAttempts: 2,
+			wantDelays:   []time.Duration{7 * time.Second},
+		},
+		{
+			name:         "Retry-After as an HTTP date",
+			responses:    []func() (*http.Response, error){respond(503, map[string]string{"Retry-After": now.Add(5 * time.Second).Format(http.TimeFormat)}), respond(200, nil)},
+			wantStatus:   200,
+			wantAttempts: 2,
+			wantDelays:   []time.Duration{5 * time.Second},
+		},
+		{
+			name:         "RateLimit-Reset as a Unix timestamp",
+			responses:    []func() (*http.Response, error){respond(429, map[string]string{"RateLimit-Reset": strconv.FormatInt(now.Add(9*time.Second).Unix(), 10)}), respond(200, nil)},
+			wantStatus:   200,
+			wantAttempts: 2,
+			wantDelays:   []time.Duration{9 * time.Second},
+		},
+		{
+			name:         "RateLimit-Reset in seconds",
+			responses:    []func() (*http.Response, error){respond(429, map[string]string{"RateLimit-Reset": "4"}), respond(200, nil)},
+			wantStatus:   200,
+			wantAttempts: 2,
+			wantDelays:   []time.Duration{4 * time.Second},
+		},
+		{
+			name:         "RateLimit-Reset is ignored unless rate limited",
+			responses:    []func() (*http.Response, error){respond(502, map[string]string{"RateLimit-Reset": "30"}), respond(200, nil)},
+			wantStatus:   200,
+			wantAttempts: 2,
+			wantDelays:   []time.Duration{time.Second},
+		},
+		{
+			name:         "Server delay beyond the budget gives up",
+			responses:    []func() (*http.Response, error){respond(429, map[string]string{"Retry-After": "120"}), respond(200, nil)},
+			wantStatus:   429,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Budget covers the total wait",
+			policy:       RetryPolicy{MaxAttempts: 5, Budget: 2 * time.Second, BaseDelay: time.Second, MaxDelay: time.Second},
+			responses:    []func() (*http.Response, error){respond(502, nil)},
+			wantStatus:   502,
+			wantAttempts: 3,
+			wantDelays:   []time.Duration{time.Second, time.Second},
+		},
+		{
+			name:         "Client errors are not retried",
+			responses:    []func() (*http.Response, error){respond(404, nil), respond(200, nil)},
+			wantStatus:   404,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Internal server errors are not retried",
+			responses:    []func() (*http.Response, error){respond(500, nil), respond(200, nil)},
+			wantStatus:   500,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Non-idempotent requests are not retried",
+			method:       http.MethodPost,
+			responses:    []func() (*http.Response, error){respond(502, nil), respond(200, nil)},
+			wantStatus:   502,
+			wantAttempts: 1,
+		},
+		{
+			name:         "A single attempt disables retries",
+			policy:       RetryPolicy{MaxAttempts: 1, Budget: time.Minute, BaseDelay: time.Second, MaxDelay: time.Second},
+			responses:    []func() (*http.Response, error){respond(502, nil), respond(200, nil)},
+			wantStatus:   502,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := tc.policy
+			if policy.MaxAttempts == 0 {
+				policy = testRetryPolicy
+			}
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			next, attempts := scriptedTransport(tc.responses...)
+			transport, delays := newTestRetryTransport(next, policy, now)
+			req, _ := http.NewRequest(method, "https://gitlab.example.com/api/v4/projects/1", nil)
+
+			resp, err := transport.RoundTrip(req)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			}
+			assert.Equal(t, tc.wantAttempts, *attempts)
+			assert.Equal(t, tc.wantDelays, *delays)
+		})
+	}
+}
+
+func TestRetryTransport_ContextDeadline(t *testing.T) {
+	now := time.Now()
+
+	t.Run("retries that would outlive the deadline are skipped", func(t *testing.T) {
+		next, attempts := scriptedTransport(respond(502, nil), respond(200, nil))
+		transport, delays := newTestRetryTransport(next, testRetryPolicy, now)
+
+		ctx, cancel := context.WithDeadline(context.Background(), now.Add(500*time.Millisecond))
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://gitlab.example.com", nil)
+
+		resp, err := transport.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 502, resp.StatusCode)
+		assert.Equal(t, 1, *attempts)
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("cancellation interrupts the wait", func(t *testing.T) {
+		next, attempts := scriptedTransport(respond(502, nil), respond(200, nil))
+		transport := newRetryTransport(next, testRetryPolicy)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		transport.sleep = func(ctx context.Context, d time.Duration) error {
+			cancel()
+			return sleepContext(ctx, d)
+		}
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://gitlab.example.com", nil)
+
+		_, err := transport.RoundTrip(req)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, *attempts)
+	})
+}
+
+func TestEqualJitter(t *testing.T) {
+	for range 100 {
+		delay := equalJitter(time.Second)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, time.Second)
+	}
+	assert.Equal(t, time.Duration(1), equalJitter(1))
+}
+
+func TestGitlabClient_RetriesTransientFailures(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name":"thumbsup","user":{"username":"alice"}}]`))
+	}))
+	defer server.Close()
+
+	client := NewGitlabClient(server.URL, "dummyToken",
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, Budget: time.Second, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+
+	emojis, err := client.ListAwardEmojis(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, emojis, 1)
+	assert.Equal(t, 2, requests)
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TransportConfig describes how to reach a provider API: which certificate
+// authorities to trust, which client certificate to present and which proxy
+// to go through. The zero value behaves like http.DefaultTransport.
+type TransportConfig struct {
+	CABundle           string // PEM file with certificate authorities trusted in addition to the system pool
+	ClientCert         string // PEM file with the client certificate for mutual TLS
+	ClientKey          string // PEM file with the private key of the client certificate
+	ProxyURL           string // Proxy for every request; the HTTP(S)_PROXY variables are used when empty
+	InsecureSkipVerify bool   // Skip server certificate verification, for testing only
+}
+
+// NewTransport builds an HTTP transport from the configuration, starting from
+// the settings of http.DefaultTransport.
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy URL '%s': expected an http, https or socks5 URL", proxyURL.Redacted())
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return transport, nil
+}
+
+// tlsConfig builds the TLS settings of the transport.
+func (cfg TransportConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			slog.Warn("Failed to load the system certificate pool, trusting only the CA bundle", "error", err)
+			pool = x509.NewCertPool()
+		}
+		bundle, err := os.ReadFile(cfg.CABundle) // #nosec G304 -- the path is operator configuration
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no PEM certificates found in CA bundle '%s'", cfg.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, fmt.Errorf("a client certificate and its key must be configured together")
+	}
+	if cfg.ClientCert != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if cfg.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification is disabled, the provider API connection is not authenticated")
+		tlsConfig.InsecureSkipVerify = true // #nosec G402 -- explicit opt-in
+	}
+
+	return tlsConfig, nil
+}
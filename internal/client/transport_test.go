@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM writes a PEM block to a file in the test's temporary directory.
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return file
+}
+
+// newClientCertificate creates a self-signed client certificate and returns
+// it parsed along with the paths of its certificate and key files.
+func newClientCertificate(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "emoji-gate"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return certificate, writePEM(t, "client.pem", "CERTIFICATE", der), writePEM(t, "client-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+// get sends a GET request through the transport and returns the status code.
+func get(transport http.RoundTripper, url string) (int, error) {
+	resp, err := (&http.Client{Transport: transport}).Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return resp.StatusCode, nil
+}
+
+func TestNewTransport_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	t.Run("untrusted server is rejected", func(t *testing.T) {
+		transport, err := NewTransport(TransportConfig{})
+		require.NoError(t, err)
+		_, err = get(transport, server.URL)
+		assert.ErrorContains(t, err, "certificate")
+	})
+
+	t.Run("server signed by the CA bundle is trusted", func(t *testing.T) {
+		bundle := writePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+		transport, err := NewTransport(TransportConfig{CABundle: bundle})
+		require.NoError(t, err)
+		status, err := get(transport, server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("verification can be skipped", func(t *testing.T) {
+		transport, err := NewTransport(TransportConfig{InsecureSkipVerify: true})
+		require.NoError(t, err)
+		status, err := get(transport, server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	})
+}
+
+func TestNewTransport_ClientCertificate(t *testing.T) {
+	certificate, certFile, keyFile := newClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(certificate)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "emoji-gate", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	bundle := writePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	t.Run("without a client certificate", func(t *testing.T) {
+		transport, err := NewTransport(TransportConfig{CABundle: bundle})
+		require.NoError(t, err)
+		_, err = get(transport, server.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("with a client certificate", func(t *testing.T) {
+		transport, err := NewTransport(TransportConfig{CABundle: bundle, ClientCert: certFile, ClientKey: keyFile})
+		require.NoError(t, err)
+		status, err := get(transport, server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	})
+}
+
+func TestNewTransport_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	transport, err := NewTransport(TransportConfig{ProxyURL: proxy.URL})
+	require.NoError(t, err)
+	status, err := get(transport, "http://gitlab.example.com/api/v4/projects")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "http://gitlab.example.com/api/v4/projects", proxied)
+}
+
+func TestNewTransport_Errors(t *testing.T) {
+	_, certFile, keyFile := newClientCertificate(t)
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	testCases := []struct {
+		name    string
+		cfg     TransportConfig
+		wantErr string
+	}{
+		{"Missing CA bundle", TransportConfig{CABundle: filepath.Join(t.TempDir(), "missing.pem")}, "failed to read CA bundle"},
+		{"CA bundle without certificates", TransportConfig{CABundle: notPEM}, "no PEM certificates found"},
+		{"Certificate without key", TransportConfig{ClientCert: certFile}, "must be configured together"},
+		{"Key without certificate", TransportConfig{ClientKey: keyFile}, "must be configured together"},
+		{"Mismatched key pair", TransportConfig{ClientCert: certFile, ClientKey: notPEM}, "failed to load client certificate"},
+		{"Unparsable proxy URL", TransportConfig{ProxyURL: "http://proxy:port"}, "invalid proxy URL"},
+		{"Unsupported proxy scheme", TransportConfig{ProxyURL: "ftp://proxy.example.com"}, "expected an http, https or socks5 URL"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTransport(tc.cfg)
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestWithTransport(t *testing.T) {
+	var used bool
+	transport := &MockRoundTripper{RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+		used = true
+		return newMockTransport(http.StatusOK, "{}", nil).RoundTrip(req)
+	}}
+
+	client := NewGitlabClient("gitlab.example.com", "dummyToken", WithTransport(transport))
+	_, err := client.GetProject(context.Background(), "group/project")
+	assert.NoError(t, err)
+	assert.True(t, used)
+}
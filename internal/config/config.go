@@ -1,31 +1,311 @@
 package config
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	envConfig "github.com/caarlos0/env/v11"
 )
 
-// GitlabConfig holds the configuration parsed from environment variables
-// required to interact with the GitLab API and evaluate merge request approvals.
-type GitlabConfig struct {
-	URL            string `env:"ATLANTIS_GITLAB_HOSTNAME,required,notEmpty"`
-	Token          string `env:"ATLANTIS_GITLAB_TOKEN,required,notEmpty"`
-	ApproveEmoji   string `env:"APPROVE_EMOJI,notEmpty" envDefault:"thumbsup"`
-	BaseRepoOwner  string `env:"BASE_REPO_OWNER,required,notEmpty"`
-	BaseRepoName   string `env:"BASE_REPO_NAME,required,notEmpty"`
-	PullRequestID  int    `env:"PULL_NUM,required,notEmpty"`
-	TerraformPath  string `env:"REPO_REL_DIR,required,notEmpty"`
-	CodeOwnersPath string `env:"CODEOWNERS_PATH,notEmpty" envDefault:"CODEOWNERS"`
-	CodeOwnersRepo string `env:"CODEOWNERS_REPO"` // Optional, if not provided, will use BaseRepoOwner/BaseRepoName
-	MrAuthor       string `env:"PULL_AUTHOR,required,notEmpty"`
-	Insecure       bool   `env:"INSECURE,notEmpty" envDefault:"false"`   // If MR author allowed to approve his own MR
-	Restricted     bool   `env:"RESTRICTED,notEmpty" envDefault:"false"` // A feature toggle that will enforce emoji timestamp validation
-}
-
-// NewGitlabConfig parses environment variables into GitlabConfig.
-func NewGitlabConfig() (GitlabConfig, error) {
-	cfg, err := envConfig.ParseAs[GitlabConfig]()
+// Supported decision explanation formats.
+const (
+	ExplainText = "text"
+	ExplainJSON = "json"
+)
+
+// Supported code hosting providers.
+const (
+	ProviderGitLab    = "gitlab"
+	ProviderGitHub    = "github"
+	ProviderGitea     = "gitea"
+	ProviderBitbucket = "bitbucket"
+)
+
+// Supported GitLab authentication modes.
+const (
+	GitlabAuthPrivateToken = "private-token"
+	GitlabAuthOAuth        = "oauth"
+	GitlabAuthJobToken     = "job-token"
+)
+
+// CodeOwnersAuto is the CODEOWNERS_PATH that looks for CODEOWNERS in the
+// standard locations of the provider.
+const CodeOwnersAuto = "auto"
+
+// Supported sources of the ref CODEOWNERS is read from.
+const (
+	RefSourceDefaultBranch = "default-branch"
+	RefSourceTargetBranch  = "target-branch"
+	RefSourcePinned        = "pinned"
+)
+
+// Supported approval sources.
+const (
+	ApprovalSourceEmoji        = "emoji"
+	ApprovalSourceMergeRequest = "approvals"
+	ApprovalSourceComment      = "comments"
+)
+
+// Supported ways of combining several approval sources.
+const (
+	SourceModeAnyOf = "any-of"
+	SourceModeAllOf = "all-of"
+)
+
+// Config holds the configuration parsed from environment variables
+// required to interact with the provider API and evaluate merge request approvals.
+// URL and Token are resolved from the Atlantis variables of the selected provider.
+// TokenFile, when set, replaces Token for GitLab and is re-read on every request.
+type Config struct {
+	Provider            string `env:"VCS_PROVIDER"` // Optional, detected from the Atlantis environment when empty
+	URL                 string
+	Token               string
+	TokenFile           string
+	GitlabAuthMode      string            `env:"GITLAB_AUTH_MODE,notEmpty" envDefault:"private-token"` // How the GitLab token is sent: private-token, oauth or job-token
+	ApproveEmojis       []string          `env:"APPROVE_EMOJI,notEmpty" envSeparator:"," envDefault:"thumbsup"`
+	ApproveEmojiScopes  map[string]string `env:"APPROVE_EMOJI_SCOPES"`                             // Optional, emoji:section pairs limiting an emoji to one CODEOWNERS section
+	NormalizeSkinTones  bool              `env:"NORMALIZE_SKIN_TONES,notEmpty" envDefault:"false"` // Treat skin-tone variants (e.g. thumbsup_tone3) as their base emoji
+	BaseRepoOwner       string            `env:"BASE_REPO_OWNER,required,notEmpty"`
+	BaseRepoName        string            `env:"BASE_REPO_NAME,required,notEmpty"`
+	PullRequestID       int               `env:"PULL_NUM,required,notEmpty"`
+	TerraformPath       string            `env:"REPO_REL_DIR,required,notEmpty"`
+	CodeOwnersPath      string            `env:"CODEOWNERS_PATH,notEmpty" envDefault:"CODEOWNERS"` // Path in the repository, or "auto" to search the standard locations
+	CodeOwnersRepo      string            `env:"CODEOWNERS_REPO"`                                  // Optional, if not provided, will use BaseRepoOwner/BaseRepoName
+	CodeOwnersRefSource string            `env:"CODEOWNERS_REF_SOURCE"`                            // Optional, default-branch, target-branch or pinned
+	CodeOwnersRef       string            `env:"CODEOWNERS_REF"`                                   // Optional, branch, tag or commit to read CODEOWNERS from
+	MrAuthor            string            `env:"PULL_AUTHOR,required,notEmpty"`
+	Insecure            bool              `env:"INSECURE,notEmpty" envDefault:"false"`                                        // If MR author allowed to approve his own MR
+	Restricted          bool              `env:"RESTRICTED,notEmpty" envDefault:"false"`                                      // Only accept approvals given after the head commit was pushed
+	CommitDateFallback  bool              `env:"RESTRICTED_COMMIT_DATE_FALLBACK,notEmpty" envDefault:"false"`                 // Use the author-controlled commit date when push times are unavailable
+	RequiredApprovals   int               `env:"REQUIRED_APPROVALS,notEmpty" envDefault:"1"`                                  // Distinct code owner approvals per section, unless the section sets [N]
+	BlockEmoji          []string          `env:"BLOCK_EMOJI" envSeparator:","`                                                // Optional, emojis that let a code owner veto the apply
+	ApprovalSources     []string          `env:"APPROVAL_SOURCES,notEmpty" envSeparator:"," envDefault:"emoji"`               // Where approvals come from: emoji reactions and/or native MR approvals
+	ApproveCommands     []string          `env:"APPROVE_COMMANDS,notEmpty" envSeparator:"," envDefault:"/emoji-gate approve"` // Note commands that approve the MR with the comments source
+	ApprovalSourceMode  string            `env:"APPROVAL_SOURCE_MODE,notEmpty" envDefault:"any-of"`                           // Whether an approval through any-of or all-of the sources counts
+	ChangedFiles        bool              `env:"CHANGED_FILES,notEmpty" envDefault:"false"`                                   // Also require approval from the owners of every file the MR changes
+	MaxApprovalAge      time.Duration     `env:"MAX_APPROVAL_AGE,notEmpty" envDefault:"0s"`                                   // Approvals older than this must be renewed, 0 disables expiry
+	Explain             string            `env:"EXPLAIN"`                                                                     // Optional, print a decision explanation as "text" or "json"
+	PostComment         bool              `env:"POST_COMMENT,notEmpty" envDefault:"false"`                                    // Post or update a note with the decision on the MR
+	CommitStatus        bool              `env:"COMMIT_STATUS,notEmpty" envDefault:"false"`                                   // Publish the decision as a commit status on the MR head
+	CommitStatusName    string            `env:"COMMIT_STATUS_NAME,notEmpty" envDefault:"emoji-gate"`                         // Commit status name prefix, followed by the Terraform path
+	RetryMaxAttempts    int               `env:"RETRY_MAX_ATTEMPTS,notEmpty" envDefault:"3"`                                  // Attempts per idempotent API request, 1 disables retries
+	RetryBudget         time.Duration     `env:"RETRY_BUDGET,notEmpty" envDefault:"20s"`                                      // Maximum total time spent waiting between retries
+	CABundle            string            `env:"TLS_CA_BUNDLE"`                                                               // Optional, PEM file with extra certificate authorities to trust
+	ClientCert          string            `env:"TLS_CLIENT_CERT"`                                                             // Optional, PEM client certificate for mutual TLS
+	ClientKey           string            `env:"TLS_CLIENT_KEY"`                                                              // Optional, PEM private key of the client certificate
+	TLSSkipVerify       bool              `env:"TLS_SKIP_VERIFY,notEmpty" envDefault:"false"`                                 // Disable server certificate verification
+	ProxyURL            string            `env:"PROXY_URL"`                                                                   // Optional, proxy for API requests instead of HTTP(S)_PROXY
+}
+
+// NewConfig parses environment variables into Config.
+func NewConfig() (Config, error) {
+	cfg, err := envConfig.ParseAs[Config]()
 	if err != nil {
-		return GitlabConfig{}, err
+		return Config{}, err
+	}
+	if err := resolveProvider(&cfg); err != nil {
+		return Config{}, err
+	}
+	cfg.ApproveEmojis = trimEach(cfg.ApproveEmojis)
+	cfg.BlockEmoji = trimEach(cfg.BlockEmoji)
+	if cfg.RequiredApprovals < 1 {
+		return Config{}, fmt.Errorf("REQUIRED_APPROVALS must be at least 1, got %d", cfg.RequiredApprovals)
+	}
+	if cfg.RetryMaxAttempts < 1 {
+		return Config{}, fmt.Errorf("RETRY_MAX_ATTEMPTS must be at least 1, got %d", cfg.RetryMaxAttempts)
+	}
+	if cfg.RetryBudget < 0 {
+		return Config{}, fmt.Errorf("RETRY_BUDGET must not be negative, got %s", cfg.RetryBudget)
+	}
+	if cfg.MaxApprovalAge < 0 {
+		return Config{}, fmt.Errorf("MAX_APPROVAL_AGE must not be negative, got %s", cfg.MaxApprovalAge)
+	}
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return Config{}, fmt.Errorf("TLS_CLIENT_CERT and TLS_CLIENT_KEY must be set together")
+	}
+	if err := resolveCodeOwnersRef(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := validateApprovalSources(&cfg); err != nil {
+		return Config{}, err
+	}
+	switch cfg.GitlabAuthMode {
+	case GitlabAuthPrivateToken, GitlabAuthOAuth, GitlabAuthJobToken:
+	default:
+		return Config{}, fmt.Errorf("unsupported GITLAB_AUTH_MODE %q, expected %q, %q or %q",
+			cfg.GitlabAuthMode, GitlabAuthPrivateToken, GitlabAuthOAuth, GitlabAuthJobToken)
+	}
+	if err := ValidateExplainFormat(cfg.Explain); err != nil {
+		return Config{}, err
 	}
 	return cfg, nil
 }
+
+// providerEnv holds the credentials Atlantis exposes for each provider.
+type providerEnv struct {
+	GitlabHostname  string `env:"ATLANTIS_GITLAB_HOSTNAME"`
+	GitlabToken     string `env:"ATLANTIS_GITLAB_TOKEN"`
+	GitlabTokenFile string `env:"GITLAB_TOKEN_FILE"`
+	GithubHostname  string `env:"ATLANTIS_GH_HOSTNAME" envDefault:"github.com"`
+	GithubToken     string `env:"ATLANTIS_GH_TOKEN"`
+	GiteaBaseURL    string `env:"ATLANTIS_GITEA_BASE_URL" envDefault:"https://gitea.com"`
+	GiteaToken      string `env:"ATLANTIS_GITEA_TOKEN"`
+	BitbucketURL    string `env:"ATLANTIS_BITBUCKET_BASE_URL"`
+	BitbucketToken  string `env:"ATLANTIS_BITBUCKET_TOKEN"`
+}
+
+// providerCredentials pairs a provider with its URL and token, along with the
+// names of the variables they were read from. Providers that can read their
+// token from a file also name the variable holding its path.
+type providerCredentials struct {
+	provider     string
+	urlVar       string
+	tokenVar     string
+	url          string
+	token        string
+	tokenFileVar string
+	tokenFile    string
+}
+
+// hasToken reports whether a token or a token file is configured.
+func (c providerCredentials) hasToken() bool {
+	return c.token != "" || c.tokenFile != ""
+}
+
+// credentials lists the credentials of every supported provider.
+func (e providerEnv) credentials() []providerCredentials {
+	return []providerCredentials{
+		{ProviderGitLab, "ATLANTIS_GITLAB_HOSTNAME", "ATLANTIS_GITLAB_TOKEN", e.GitlabHostname, e.GitlabToken, "GITLAB_TOKEN_FILE", e.GitlabTokenFile},
+		{ProviderGitHub, "ATLANTIS_GH_HOSTNAME", "ATLANTIS_GH_TOKEN", e.GithubHostname, e.GithubToken, "", ""},
+		{ProviderGitea, "ATLANTIS_GITEA_BASE_URL", "ATLANTIS_GITEA_TOKEN", e.GiteaBaseURL, e.GiteaToken, "", ""},
+		{ProviderBitbucket, "ATLANTIS_BITBUCKET_BASE_URL", "ATLANTIS_BITBUCKET_TOKEN", e.BitbucketURL, e.BitbucketToken, "", ""},
+	}
+}
+
+// resolveProvider selects the provider, detecting it from the Atlantis
+// environment unless VCS_PROVIDER is set, and fills in its URL and token.
+func resolveProvider(cfg *Config) error {
+	env, err := envConfig.ParseAs[providerEnv]()
+	if err != nil {
+		return err
+	}
+	candidates := env.credentials()
+
+	if cfg.Provider == "" {
+		var configured, tokenVars []string
+		for _, c := range candidates {
+			tokenVars = append(tokenVars, c.tokenVar)
+			if c.hasToken() {
+				configured = append(configured, c.provider)
+			}
+		}
+		switch len(configured) {
+		case 0:
+			return fmt.Errorf("unable to detect the VCS provider, set one of %s", strings.Join(tokenVars, ", "))
+		case 1:
+			cfg.Provider = configured[0]
+		default:
+			return fmt.Errorf("credentials for several providers (%s) are set, set VCS_PROVIDER to choose one", strings.Join(configured, ", "))
+		}
+	}
+
+	var supported []string
+	for _, c := range candidates {
+		supported = append(supported, c.provider)
+		if c.provider != cfg.Provider {
+			continue
+		}
+		if c.url == "" || !c.hasToken() {
+			if c.tokenFileVar != "" {
+				return fmt.Errorf("%s and %s (or %s) are required for the %s provider", c.urlVar, c.tokenVar, c.tokenFileVar, c.provider)
+			}
+			return fmt.Errorf("%s and %s are required for the %s provider", c.urlVar, c.tokenVar, c.provider)
+		}
+		if c.provider == ProviderBitbucket && strings.Contains(c.url, "bitbucket.org") {
+			return fmt.Errorf("bitbucket Cloud is not supported, only Bitbucket Server and Data Center")
+		}
+		cfg.URL, cfg.Token, cfg.TokenFile = c.url, c.token, c.tokenFile
+		return nil
+	}
+	return fmt.Errorf("unsupported VCS_PROVIDER %q, expected one of %s", cfg.Provider, strings.Join(supported, ", "))
+}
+
+// resolveCodeOwnersRef validates where CODEOWNERS is read from. Without an
+// explicit source, a configured ref is pinned and the default branch is used otherwise.
+// The target branch belongs to the merge request's repository, so it cannot be
+// combined with a separate CODEOWNERS repository.
+func resolveCodeOwnersRef(cfg *Config) error {
+	switch cfg.CodeOwnersRefSource {
+	case "":
+		cfg.CodeOwnersRefSource = RefSourceDefaultBranch
+		if cfg.CodeOwnersRef != "" {
+			cfg.CodeOwnersRefSource = RefSourcePinned
+		}
+	case RefSourcePinned:
+		if cfg.CodeOwnersRef == "" {
+			return fmt.Errorf("CODEOWNERS_REF is required when CODEOWNERS_REF_SOURCE is %q", RefSourcePinned)
+		}
+	case RefSourceDefaultBranch, RefSourceTargetBranch:
+		if cfg.CodeOwnersRef != "" {
+			return fmt.Errorf("CODEOWNERS_REF is only used when CODEOWNERS_REF_SOURCE is %q, got %q", RefSourcePinned, cfg.CodeOwnersRefSource)
+		}
+		if cfg.CodeOwnersRefSource == RefSourceTargetBranch && cfg.CodeOwnersRepo != "" {
+			return fmt.Errorf("CODEOWNERS_REF_SOURCE %q cannot be used with CODEOWNERS_REPO, whose branches are not the merge request's; use %q or %q instead",
+				RefSourceTargetBranch, RefSourceDefaultBranch, RefSourcePinned)
+		}
+	default:
+		return fmt.Errorf("unsupported CODEOWNERS_REF_SOURCE %q, expected %q, %q or %q",
+			cfg.CodeOwnersRefSource, RefSourceDefaultBranch, RefSourceTargetBranch, RefSourcePinned)
+	}
+	return nil
+}
+
+// validateApprovalSources checks the approval sources and how they are combined.
+func validateApprovalSources(cfg *Config) error {
+	cfg.ApprovalSources = trimEach(cfg.ApprovalSources)
+	if len(cfg.ApprovalSources) == 0 {
+		return fmt.Errorf("APPROVAL_SOURCES must list at least one source")
+	}
+	for _, source := range cfg.ApprovalSources {
+		switch source {
+		case ApprovalSourceEmoji, ApprovalSourceMergeRequest, ApprovalSourceComment:
+		default:
+			return fmt.Errorf("unsupported approval source %q, expected %q, %q or %q",
+				source, ApprovalSourceEmoji, ApprovalSourceMergeRequest, ApprovalSourceComment)
+		}
+	}
+	cfg.ApproveCommands = trimEach(cfg.ApproveCommands)
+	if len(cfg.ApproveCommands) == 0 {
+		return fmt.Errorf("APPROVE_COMMANDS must list at least one command")
+	}
+	switch cfg.ApprovalSourceMode {
+	case SourceModeAnyOf, SourceModeAllOf:
+		return nil
+	default:
+		return fmt.Errorf("unsupported APPROVAL_SOURCE_MODE %q, expected %q or %q", cfg.ApprovalSourceMode, SourceModeAnyOf, SourceModeAllOf)
+	}
+}
+
+// trimEach removes surrounding whitespace from every list entry and drops
+// empty ones, so "thumbsup, white_check_mark" is accepted.
+func trimEach(values []string) []string {
+	trimmed := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
+
+// ValidateExplainFormat checks that an explanation format is supported.
+// An empty format disables the explanation.
+func ValidateExplainFormat(format string) error {
+	switch format {
+	case "", ExplainText, ExplainJSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported explanation format %q, expected %q or %q", format, ExplainText, ExplainJSON)
+	}
+}
@@ -2,13 +2,14 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestNewGitlabConfig(t *testing.T) {
+func TestNewConfig(t *testing.T) {
 	t.Run("missing required environment variables", func(t *testing.T) {
-		_, err := NewGitlabConfig()
+		_, err := NewConfig()
 		assert.Error(t, err)
 	})
 
@@ -25,19 +26,403 @@ func TestNewGitlabConfig(t *testing.T) {
 		t.Setenv("REPO_REL_DIR", "terraform/provision")
 
 		// Call the function to test
-		cfg, err := NewGitlabConfig()
+		cfg, err := NewConfig()
 
 		assert.NoError(t, err)
 
 		// Assert the expected values
+		assert.Equal(t, ProviderGitLab, cfg.Provider)
 		assert.Equal(t, "gitlab.example.com", cfg.URL)
 		assert.Equal(t, "example-token", cfg.Token)
-		assert.Equal(t, "thumbsup", cfg.ApproveEmoji)
+		assert.Equal(t, []string{"thumbsup"}, cfg.ApproveEmojis)
 		assert.Equal(t, "example-author", cfg.MrAuthor)
 		assert.Equal(t, "example-owner", cfg.BaseRepoOwner)
 		assert.Equal(t, "example-repo", cfg.BaseRepoName)
 		assert.Equal(t, 123, cfg.PullRequestID)
 		assert.Equal(t, ".test/CODEOWNERS", cfg.CodeOwnersPath)
 		assert.Equal(t, "terraform/provision", cfg.TerraformPath)
+		assert.Equal(t, 1, cfg.RequiredApprovals)
+		assert.Empty(t, cfg.BlockEmoji)
+		assert.False(t, cfg.PostComment)
+		assert.False(t, cfg.ChangedFiles)
+		assert.Equal(t, []string{ApprovalSourceEmoji}, cfg.ApprovalSources)
+		assert.Equal(t, SourceModeAnyOf, cfg.ApprovalSourceMode)
+		assert.Equal(t, []string{"/emoji-gate approve"}, cfg.ApproveCommands)
+		assert.False(t, cfg.CommitStatus)
+		assert.Equal(t, "emoji-gate", cfg.CommitStatusName)
+		assert.Equal(t, 3, cfg.RetryMaxAttempts)
+		assert.Equal(t, 20*time.Second, cfg.RetryBudget)
+		assert.Zero(t, cfg.MaxApprovalAge)
 	})
+
+	t.Run("blocking emojis are parsed as a list", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+		t.Setenv("BLOCK_EMOJI", "thumbsdown,no_entry")
+
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"thumbsdown", "no_entry"}, cfg.BlockEmoji)
+	})
+
+	t.Run("approval emojis, scopes and normalization", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+		t.Setenv("APPROVE_EMOJI", "thumbsup, white_check_mark,")
+		t.Setenv("APPROVE_EMOJI_SCOPES", "lock:Security,books:Docs")
+		t.Setenv("NORMALIZE_SKIN_TONES", "true")
+
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"thumbsup", "white_check_mark"}, cfg.ApproveEmojis)
+		assert.Equal(t, map[string]string{"lock": "Security", "books": "Docs"}, cfg.ApproveEmojiScopes)
+		assert.True(t, cfg.NormalizeSkinTones)
+	})
+
+	t.Run("required approvals must be positive", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+		t.Setenv("REQUIRED_APPROVALS", "0")
+
+		_, err := NewConfig()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "REQUIRED_APPROVALS must be at least 1")
+	})
+
+	t.Run("retry settings are validated", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+		t.Setenv("RETRY_MAX_ATTEMPTS", "0")
+
+		_, err := NewConfig()
+		assert.ErrorContains(t, err, "RETRY_MAX_ATTEMPTS must be at least 1")
+
+		t.Setenv("RETRY_MAX_ATTEMPTS", "5")
+		t.Setenv("RETRY_BUDGET", "-1s")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, "RETRY_BUDGET must not be negative")
+
+		t.Setenv("RETRY_BUDGET", "1m")
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, 5, cfg.RetryMaxAttempts)
+		assert.Equal(t, time.Minute, cfg.RetryBudget)
+	})
+
+	t.Run("maximum approval age", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+		t.Setenv("MAX_APPROVAL_AGE", "-1h")
+
+		_, err := NewConfig()
+		assert.ErrorContains(t, err, "MAX_APPROVAL_AGE must not be negative")
+
+		t.Setenv("MAX_APPROVAL_AGE", "72h")
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, 72*time.Hour, cfg.MaxApprovalAge)
+	})
+
+	t.Run("client certificate and key are set together", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+		t.Setenv("TLS_CLIENT_CERT", "/etc/ssl/client.pem")
+
+		_, err := NewConfig()
+		assert.ErrorContains(t, err, "TLS_CLIENT_CERT and TLS_CLIENT_KEY must be set together")
+
+		t.Setenv("TLS_CLIENT_KEY", "/etc/ssl/client-key.pem")
+		t.Setenv("TLS_CA_BUNDLE", "/etc/ssl/ca.pem")
+		t.Setenv("TLS_SKIP_VERIFY", "true")
+		t.Setenv("PROXY_URL", "http://proxy.example.com:3128")
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, "/etc/ssl/client.pem", cfg.ClientCert)
+		assert.Equal(t, "/etc/ssl/client-key.pem", cfg.ClientKey)
+		assert.Equal(t, "/etc/ssl/ca.pem", cfg.CABundle)
+		assert.True(t, cfg.TLSSkipVerify)
+		assert.Equal(t, "http://proxy.example.com:3128", cfg.ProxyURL)
+	})
+
+	t.Run("GitLab authentication mode", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, GitlabAuthPrivateToken, cfg.GitlabAuthMode)
+
+		t.Setenv("GITLAB_AUTH_MODE", "job-token")
+		cfg, err = NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, GitlabAuthJobToken, cfg.GitlabAuthMode)
+
+		t.Setenv("GITLAB_AUTH_MODE", "basic")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, `unsupported GITLAB_AUTH_MODE "basic"`)
+	})
+
+	t.Run("approval sources", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+
+		t.Setenv("APPROVAL_SOURCES", "emoji, approvals")
+		t.Setenv("APPROVAL_SOURCE_MODE", "all-of")
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{ApprovalSourceEmoji, ApprovalSourceMergeRequest}, cfg.ApprovalSources)
+		assert.Equal(t, SourceModeAllOf, cfg.ApprovalSourceMode)
+
+		t.Setenv("APPROVAL_SOURCE_MODE", "majority")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, `unsupported APPROVAL_SOURCE_MODE "majority"`)
+
+		t.Setenv("APPROVAL_SOURCE_MODE", "any-of")
+		t.Setenv("APPROVAL_SOURCES", "emoji,reviews")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, `unsupported approval source "reviews"`)
+
+		t.Setenv("APPROVAL_SOURCES", " , ")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, "APPROVAL_SOURCES must list at least one source")
+
+		t.Setenv("APPROVAL_SOURCES", "comments")
+		t.Setenv("APPROVE_COMMANDS", "/lgtm, /emoji-gate approve")
+		cfg, err = NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{ApprovalSourceComment}, cfg.ApprovalSources)
+		assert.Equal(t, []string{"/lgtm", "/emoji-gate approve"}, cfg.ApproveCommands)
+
+		t.Setenv("APPROVE_COMMANDS", ",")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, "APPROVE_COMMANDS must list at least one command")
+	})
+
+	t.Run("CODEOWNERS ref source", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, RefSourceDefaultBranch, cfg.CodeOwnersRefSource)
+
+		t.Setenv("CODEOWNERS_REF", "v1.2.0")
+		cfg, err = NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, RefSourcePinned, cfg.CodeOwnersRefSource)
+		assert.Equal(t, "v1.2.0", cfg.CodeOwnersRef)
+
+		t.Setenv("CODEOWNERS_REF_SOURCE", "target-branch")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, "CODEOWNERS_REF is only used when CODEOWNERS_REF_SOURCE is \"pinned\"")
+
+		t.Setenv("CODEOWNERS_REF", "")
+		cfg, err = NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, RefSourceTargetBranch, cfg.CodeOwnersRefSource)
+
+		t.Setenv("CODEOWNERS_REPO", "platform/codeowners")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, `CODEOWNERS_REF_SOURCE "target-branch" cannot be used with CODEOWNERS_REPO`)
+
+		t.Setenv("CODEOWNERS_REF_SOURCE", "default-branch")
+		cfg, err = NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, "platform/codeowners", cfg.CodeOwnersRepo)
+
+		t.Setenv("CODEOWNERS_REPO", "")
+
+		t.Setenv("CODEOWNERS_REF_SOURCE", "pinned")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, "CODEOWNERS_REF is required")
+
+		t.Setenv("CODEOWNERS_REF_SOURCE", "latest")
+		_, err = NewConfig()
+		assert.ErrorContains(t, err, `unsupported CODEOWNERS_REF_SOURCE "latest"`)
+	})
+
+	t.Run("explanation format is validated", func(t *testing.T) {
+		t.Setenv("ATLANTIS_GITLAB_HOSTNAME", "gitlab.example.com")
+		t.Setenv("ATLANTIS_GITLAB_TOKEN", "example-token")
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+		t.Setenv("EXPLAIN", "yaml")
+
+		_, err := NewConfig()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported explanation format")
+
+		t.Setenv("EXPLAIN", "json")
+		cfg, err := NewConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, ExplainJSON, cfg.Explain)
+	})
+}
+
+func TestNewConfig_Provider(t *testing.T) {
+	setCommon := func(t *testing.T) {
+		t.Setenv("BASE_REPO_OWNER", "example-owner")
+		t.Setenv("BASE_REPO_NAME", "example-repo")
+		t.Setenv("PULL_NUM", "123")
+		t.Setenv("PULL_AUTHOR", "example-author")
+		t.Setenv("REPO_REL_DIR", "terraform/provision")
+	}
+
+	testCases := []struct {
+		name         string
+		env          map[string]string
+		wantProvider string
+		wantURL      string
+		wantToken    string
+		wantFile     string
+		wantErr      string
+	}{
+		{
+			name:         "GitLab is detected from its token",
+			env:          map[string]string{"ATLANTIS_GITLAB_HOSTNAME": "gitlab.example.com", "ATLANTIS_GITLAB_TOKEN": "gl-token"},
+			wantProvider: ProviderGitLab,
+			wantURL:      "gitlab.example.com",
+			wantToken:    "gl-token",
+		},
+		{
+			name:         "GitLab is detected from its token file",
+			env:          map[string]string{"ATLANTIS_GITLAB_HOSTNAME": "gitlab.example.com", "GITLAB_TOKEN_FILE": "/run/secrets/gitlab-token"},
+			wantProvider: ProviderGitLab,
+			wantURL:      "gitlab.example.com",
+			wantFile:     "/run/secrets/gitlab-token",
+		},
+		{
+			name:         "GitHub is detected from its token and defaults to github.com",
+			env:          map[string]string{"ATLANTIS_GH_TOKEN": "gh-token"},
+			wantProvider: ProviderGitHub,
+			wantURL:      "github.com",
+			wantToken:    "gh-token",
+		},
+		{
+			name:         "GitHub Enterprise hostname",
+			env:          map[string]string{"ATLANTIS_GH_TOKEN": "gh-token", "ATLANTIS_GH_HOSTNAME": "github.example.com"},
+			wantProvider: ProviderGitHub,
+			wantURL:      "github.example.com",
+			wantToken:    "gh-token",
+		},
+		{
+			name: "Explicit provider wins when several are configured",
+			env: map[string]string{
+				"VCS_PROVIDER":             "github",
+				"ATLANTIS_GITLAB_HOSTNAME": "gitlab.example.com",
+				"ATLANTIS_GITLAB_TOKEN":    "gl-token",
+				"ATLANTIS_GH_TOKEN":        "gh-token",
+			},
+			wantProvider: ProviderGitHub,
+			wantURL:      "github.com",
+			wantToken:    "gh-token",
+		},
+		{
+			name:         "Gitea is detected from its token",
+			env:          map[string]string{"ATLANTIS_GITEA_TOKEN": "gitea-token", "ATLANTIS_GITEA_BASE_URL": "https://forgejo.example.com"},
+			wantProvider: ProviderGitea,
+			wantURL:      "https://forgejo.example.com",
+			wantToken:    "gitea-token",
+		},
+		{
+			name:         "Bitbucket Server is detected from its token",
+			env:          map[string]string{"ATLANTIS_BITBUCKET_TOKEN": "bb-token", "ATLANTIS_BITBUCKET_BASE_URL": "https://bitbucket.example.com"},
+			wantProvider: ProviderBitbucket,
+			wantURL:      "https://bitbucket.example.com",
+			wantToken:    "bb-token",
+		},
+		{
+			name:    "Bitbucket Cloud is rejected",
+			env:     map[string]string{"ATLANTIS_BITBUCKET_TOKEN": "bb-token", "ATLANTIS_BITBUCKET_BASE_URL": "https://api.bitbucket.org"},
+			wantErr: "bitbucket Cloud is not supported",
+		},
+		{
+			name:    "Ambiguous provider",
+			env:     map[string]string{"ATLANTIS_GITLAB_TOKEN": "gl-token", "ATLANTIS_GH_TOKEN": "gh-token"},
+			wantErr: "set VCS_PROVIDER to choose one",
+		},
+		{
+			name:    "No provider credentials",
+			env:     map[string]string{},
+			wantErr: "unable to detect the VCS provider",
+		},
+		{
+			name:    "GitLab without hostname",
+			env:     map[string]string{"ATLANTIS_GITLAB_TOKEN": "gl-token"},
+			wantErr: "ATLANTIS_GITLAB_HOSTNAME and ATLANTIS_GITLAB_TOKEN (or GITLAB_TOKEN_FILE) are required",
+		},
+		{
+			name:    "Unsupported provider",
+			env:     map[string]string{"VCS_PROVIDER": "svn"},
+			wantErr: `unsupported VCS_PROVIDER "svn"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setCommon(t)
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := NewConfig()
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantProvider, cfg.Provider)
+			assert.Equal(t, tc.wantURL, cfg.URL)
+			assert.Equal(t, tc.wantToken, cfg.Token)
+			assert.Equal(t, tc.wantFile, cfg.TokenFile)
+		})
+	}
 }
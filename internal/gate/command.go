@@ -0,0 +1,69 @@
+package gate
+
+import (
+	"path"
+	"strings"
+	"unicode"
+)
+
+// approvalCommand is an approval given with a command in a merge request note,
+// such as "/emoji-gate approve terraform/deploy".
+type approvalCommand struct {
+	// paths limits the approval to these directories and everything beneath
+	// them. An empty list approves every directory of the merge request.
+	paths []string
+	// reason is the text on the lines following the command.
+	reason string
+}
+
+// parseApprovalCommand finds the first line of the note starting with one of
+// the commands, which are matched case-insensitively. The words following the
+// command are the paths the approval is limited to.
+func parseApprovalCommand(body string, commands []string) (approvalCommand, bool) {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		for _, command := range commands {
+			if len(line) < len(command) || !strings.EqualFold(line[:len(command)], command) {
+				continue
+			}
+			args := line[len(command):]
+			if args != "" && !unicode.IsSpace(rune(args[0])) {
+				// A longer word such as "/approved" is not the command.
+				continue
+			}
+			return approvalCommand{
+				paths:  strings.Fields(args),
+				reason: strings.TrimSpace(strings.Join(lines[i+1:], "\n")),
+			}, true
+		}
+	}
+	return approvalCommand{}, false
+}
+
+// covers reports whether the approval applies to the Terraform directory,
+// either because it is not limited to any path or because the directory is
+// one of its paths or lies beneath one of them.
+func (c approvalCommand) covers(terraformPath string) bool {
+	if len(c.paths) == 0 {
+		return true
+	}
+	dir := cleanPath(terraformPath)
+	for _, p := range c.paths {
+		p = cleanPath(p)
+		if p == "." || dir == p || strings.HasPrefix(dir, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanPath normalizes a repository-relative path, so "./terraform/" and
+// "terraform" compare equal.
+func cleanPath(p string) string {
+	cleaned := strings.Trim(path.Clean("/"+p), "/")
+	if cleaned == "" {
+		return "."
+	}
+	return cleaned
+}
@@ -0,0 +1,65 @@
+package gate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseApprovalCommand(t *testing.T) {
+	commands := []string{"/emoji-gate approve", "/lgtm"}
+
+	testCases := []struct {
+		name       string
+		body       string
+		wantOK     bool
+		wantPaths  []string
+		wantReason string
+	}{
+		{"Bare command", "/emoji-gate approve", true, []string{}, ""},
+		{"Command with a path", "/emoji-gate approve terraform/deploy", true, []string{"terraform/deploy"}, ""},
+		{"Command with several paths", "/emoji-gate approve terraform/deploy  modules/vpc", true, []string{"terraform/deploy", "modules/vpc"}, ""},
+		{"Reason on the following lines", "/lgtm\nChecked the plan,\nno destroys.", true, []string{}, "Checked the plan,\nno destroys."},
+		{"Command after other text", "Thanks!\n  /LGTM terraform", true, []string{"terraform"}, ""},
+		{"Longer word is not the command", "/lgtmx", false, nil, ""},
+		{"Command in the middle of a line", "please /lgtm", false, nil, ""},
+		{"Quoted command", "> /lgtm", false, nil, ""},
+		{"No command", "looks good to me", false, nil, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			command, ok := parseApprovalCommand(tc.body, commands)
+			assert.Equal(t, tc.wantOK, ok)
+			if tc.wantOK {
+				assert.Equal(t, tc.wantPaths, command.paths)
+				assert.Equal(t, tc.wantReason, command.reason)
+			}
+		})
+	}
+}
+
+func TestApprovalCommand_Covers(t *testing.T) {
+	testCases := []struct {
+		name  string
+		paths []string
+		dir   string
+		want  bool
+	}{
+		{"Unlimited", nil, "terraform/deploy", true},
+		{"Same directory", []string{"terraform/deploy"}, "terraform/deploy", true},
+		{"Parent directory", []string{"terraform"}, "terraform/deploy", true},
+		{"Normalized paths", []string{"./terraform/"}, "/terraform/deploy", true},
+		{"Repository root", []string{"."}, "terraform/deploy", true},
+		{"Sibling directory", []string{"terraform/provision"}, "terraform/deploy", false},
+		{"Name prefix is not a parent", []string{"terra"}, "terraform/deploy", false},
+		{"Subdirectory", []string{"terraform/deploy/eu"}, "terraform/deploy", false},
+		{"Any of several paths", []string{"modules", "terraform"}, "terraform/deploy", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, approvalCommand{paths: tc.paths}.covers(tc.dir))
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package gate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
+)
+
+// commentMarker returns the hidden HTML comment identifying the note the gate
+// maintains for a Terraform path. Atlantis runs the gate once per project
+// directory, so each directory gets its own note.
+func commentMarker(terraformPath string) string {
+	return fmt.Sprintf("<!-- atlantis-emoji-gate: %s -->", terraformPath)
+}
+
+// publishComment posts the decision as a note on the merge request, updating
+// the note from a previous run in place instead of adding a new one. Only
+// notes written by the token's user are updated, since anyone can post a note
+// starting with the marker.
+func publishComment(ctx context.Context, gc client.Client, cfg config.Config, projectID int, decision *processor.Decision) error {
+	marker := commentMarker(cfg.TerraformPath)
+	body := marker + "\n" + renderComment(decision)
+
+	self, err := gc.GetCurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the user of the token: %w", err)
+	}
+
+	notes, err := gc.ListNotes(ctx, projectID, cfg.PullRequestID)
+	if err != nil {
+		return fmt.Errorf("failed to list merge request notes: %w", err)
+	}
+
+	for _, note := range notes {
+		if note.System || note.Author.Username != self.Username || !strings.HasPrefix(note.Body, marker) {
+			continue
+		}
+		if note.Body == body {
+			slog.Debug("Decision comment is up to date", "note_id", note.ID)
+			return nil
+		}
+		if _, err := gc.UpdateNote(ctx, projectID, cfg.PullRequestID, note.ID, body); err != nil {
+			return fmt.Errorf("failed to update decision comment: %w", err)
+		}
+		slog.Info("Updated decision comment", "note_id", note.ID)
+		return nil
+	}
+
+	note, err := gc.CreateNote(ctx, projectID, cfg.PullRequestID, body)
+	if err != nil {
+		return fmt.Errorf("failed to create decision comment: %w", err)
+	}
+	slog.Info("Posted decision comment", "note_id", note.ID)
+	return nil
+}
+
+// renderComment formats the decision as Markdown for reviewers on the merge request.
+func renderComment(decision *processor.Decision) string {
+	var b strings.Builder
+
+	switch {
+	case len(decision.BlockedBy) > 0:
+		fmt.Fprintf(&b, "### :no_entry: Apply blocked for `%s`\n\n", decision.Path)
+		fmt.Fprintf(&b, "Vetoed by %s.\n", mentionAll(decision.BlockedBy))
+	case decision.Approved:
+		fmt.Fprintf(&b, "### :white_check_mark: Apply approved for `%s`\n\n", decision.Path)
+	case len(decision.Sections) == 0:
+		fmt.Fprintf(&b, "### :x: Apply not approved for `%s`\n\n", decision.Path)
+		b.WriteString("No CODEOWNERS rule matches this directory, so nobody can approve it.\n")
+		return b.String()
+	default:
+		fmt.Fprintf(&b, "### :hourglass: Waiting for code owner approval for `%s`\n\n", decision.Path)
+	}
+
+	fmt.Fprintf(&b, "%d of %d required approvals found", decision.Found, decision.Required)
+	if len(decision.Paths) > 0 {
+		fmt.Fprintf(&b, ", covering the owners of %d paths including the changed files", len(decision.Paths))
+	}
+	b.WriteString(".\n\n")
+	b.WriteString("| Section | Rule | Owners | Approved by | Missing |\n")
+	b.WriteString("|---------|------|--------|-------------|---------|\n")
+	for _, section := range decision.Sections {
+		name := section.Section
+		if section.Optional {
+			name += " (optional)"
+		}
+		missing := max(section.Required-len(section.Approvers), 0)
+		if section.Optional {
+			missing = 0
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %d |\n",
+			name, section.Pattern, mentionAll(section.Owners), orNone(mentionAll(section.Approvers)), missing)
+	}
+
+	return b.String()
+}
+
+// mentionAll formats usernames and group paths as code-quoted mentions, so
+// owners are not notified every time the note is updated.
+func mentionAll(names []string) string {
+	mentions := make([]string, 0, len(names))
+	for _, name := range names {
+		mentions = append(mentions, "`@"+name+"`")
+	}
+	return strings.Join(mentions, ", ")
+}
+
+// orNone substitutes an em dash for an empty table cell.
+func orNone(value string) string {
+	if value == "" {
+		return "—"
+	}
+	return value
+}
@@ -0,0 +1,194 @@
+package gate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	clientmocks "github.com/shini4i/atlantis-emoji-gate/internal/client/mocks"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRenderComment(t *testing.T) {
+	sections := []processor.SectionResult{
+		{
+			Requirement: processor.Requirement{Section: "codeowners", Pattern: "/terraform", Owners: []string{"alice", "platform/sre"}},
+			Required:    2,
+			Approvers:   []string{"alice"},
+		},
+		{
+			Requirement: processor.Requirement{Section: "Docs", Optional: true, Pattern: "*.md", Owners: []string{"writer"}},
+			Required:    1,
+		},
+	}
+
+	t.Run("Waiting for approvals", func(t *testing.T) {
+		body := renderComment(&processor.Decision{Path: "terraform/deploy", Found: 1, Required: 2, Sections: sections})
+		assert.Contains(t, body, "Waiting for code owner approval for `terraform/deploy`")
+		assert.Contains(t, body, "1 of 2 required approvals found.")
+		assert.Contains(t, body, "| codeowners | `/terraform` | `@alice`, `@platform/sre` | `@alice` | 1 |")
+		assert.Contains(t, body, "| Docs (optional) | `*.md` | `@writer` | — | 0 |")
+	})
+
+	t.Run("Changed files", func(t *testing.T) {
+		body := renderComment(&processor.Decision{Path: "terraform", Paths: []string{"terraform", "modules/vpc/main.tf"}, Found: 1, Required: 2, Sections: sections})
+		assert.Contains(t, body, "1 of 2 required approvals found, covering the owners of 2 paths including the changed files.")
+	})
+
+	t.Run("Approved", func(t *testing.T) {
+		body := renderComment(&processor.Decision{Path: "terraform", Approved: true, Found: 2, Required: 2, Sections: sections[:1]})
+		assert.Contains(t, body, "Apply approved for `terraform`")
+	})
+
+	t.Run("Blocked", func(t *testing.T) {
+		body := renderComment(&processor.Decision{Path: "terraform", BlockedBy: []string{"bob"}, Sections: sections[:1]})
+		assert.Contains(t, body, "Apply blocked for `terraform`")
+		assert.Contains(t, body, "Vetoed by `@bob`.")
+	})
+
+	t.Run("No matching rule", func(t *testing.T) {
+		body := renderComment(&processor.Decision{Path: "docs"})
+		assert.Contains(t, body, "No CODEOWNERS rule matches this directory")
+		assert.NotContains(t, body, "| Section |")
+	})
+}
+
+func TestPublishComment(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Config{PullRequestID: 5, TerraformPath: "terraform/deploy"}
+	decision := &processor.Decision{Path: "terraform/deploy", Approved: true}
+	wantBody := commentMarker("terraform/deploy") + "\n" + renderComment(decision)
+	bot := client.User{Username: "emoji-gate-bot"}
+
+	t.Run("Creates a note when none exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mc := clientmocks.NewMockClient(ctrl)
+
+		mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+		mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{
+			{ID: 1, Body: "LGTM"},
+			{ID: 2, Body: commentMarker("terraform/provision") + "\nother directory"},
+		}, nil)
+		mc.EXPECT().CreateNote(ctx, 1, 5, wantBody).Return(&client.Note{ID: 3}, nil)
+
+		assert.NoError(t, publishComment(ctx, mc, cfg, 1, decision))
+	})
+
+	t.Run("Updates the existing note in place", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mc := clientmocks.NewMockClient(ctrl)
+
+		mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+		mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{
+			{ID: 7, Body: commentMarker("terraform/deploy") + "\nstale", Author: bot},
+		}, nil)
+		mc.EXPECT().UpdateNote(ctx, 1, 5, 7, wantBody).Return(&client.Note{ID: 7}, nil)
+
+		assert.NoError(t, publishComment(ctx, mc, cfg, 1, decision))
+	})
+
+	t.Run("Leaves an up-to-date note untouched", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mc := clientmocks.NewMockClient(ctrl)
+
+		mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+		mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{{ID: 7, Body: wantBody, Author: bot}}, nil)
+
+		assert.NoError(t, publishComment(ctx, mc, cfg, 1, decision))
+	})
+
+	t.Run("Ignores system notes quoting the marker", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mc := clientmocks.NewMockClient(ctrl)
+
+		mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+		mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{{ID: 7, Body: wantBody + "x", Author: bot, System: true}}, nil)
+		mc.EXPECT().CreateNote(ctx, 1, 5, wantBody).Return(&client.Note{ID: 8}, nil)
+
+		assert.NoError(t, publishComment(ctx, mc, cfg, 1, decision))
+	})
+
+	t.Run("Ignores marker notes written by someone else", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mc := clientmocks.NewMockClient(ctrl)
+
+		forged := commentMarker("terraform/deploy") + "\n### :white_check_mark: Apply approved for `terraform/deploy`"
+		mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+		mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{
+			{ID: 6, Body: forged, Author: client.User{Username: "mallory"}},
+			{ID: 7, Body: commentMarker("terraform/deploy") + "\nstale", Author: bot},
+		}, nil)
+		mc.EXPECT().UpdateNote(ctx, 1, 5, 7, wantBody).Return(&client.Note{ID: 7}, nil)
+
+		assert.NoError(t, publishComment(ctx, mc, cfg, 1, decision))
+	})
+
+	t.Run("Creates its own note next to a forged one", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mc := clientmocks.NewMockClient(ctrl)
+
+		mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+		mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{
+			{ID: 6, Body: wantBody, Author: client.User{Username: "mallory"}},
+		}, nil)
+		mc.EXPECT().CreateNote(ctx, 1, 5, wantBody).Return(&client.Note{ID: 8}, nil)
+
+		assert.NoError(t, publishComment(ctx, mc, cfg, 1, decision))
+	})
+
+	errorCases := []struct {
+		name    string
+		setup   func(mc *clientmocks.MockClient)
+		wantErr string
+	}{
+		{
+			name: "Current user fails",
+			setup: func(mc *clientmocks.MockClient) {
+				mc.EXPECT().GetCurrentUser(ctx).Return(nil, errors.New("boom"))
+			},
+			wantErr: "failed to fetch the user of the token",
+		},
+		{
+			name: "List fails",
+			setup: func(mc *clientmocks.MockClient) {
+				mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+				mc.EXPECT().ListNotes(ctx, 1, 5).Return(nil, errors.New("boom"))
+			},
+			wantErr: "failed to list merge request notes",
+		},
+		{
+			name: "Create fails",
+			setup: func(mc *clientmocks.MockClient) {
+				mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+				mc.EXPECT().ListNotes(ctx, 1, 5).Return(nil, nil)
+				mc.EXPECT().CreateNote(ctx, 1, 5, gomock.Any()).Return(nil, errors.New("boom"))
+			},
+			wantErr: "failed to create decision comment",
+		},
+		{
+			name: "Update fails",
+			setup: func(mc *clientmocks.MockClient) {
+				mc.EXPECT().GetCurrentUser(ctx).Return(&bot, nil)
+				mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{{ID: 7, Body: commentMarker("terraform/deploy"), Author: bot}}, nil)
+				mc.EXPECT().UpdateNote(ctx, 1, 5, 7, gomock.Any()).Return(nil, errors.New("boom"))
+			},
+			wantErr: "failed to update decision comment",
+		},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mc := clientmocks.NewMockClient(ctrl)
+			tc.setup(mc)
+
+			err := publishComment(ctx, mc, cfg, 1, decision)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package gate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
+)
+
+// explainOutput is where Run writes the decision explanation. Atlantis shows
+// the standard output of workflow steps, so that is the default.
+var explainOutput io.Writer = os.Stdout
+
+// WriteExplanation renders the decision as human-readable text or as JSON,
+// listing the matched CODEOWNERS rules, their resolved owners, and a verdict
+// with a reason for every reaction.
+func WriteExplanation(w io.Writer, decision *processor.Decision, format string) error {
+	switch format {
+	case config.ExplainJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(decision)
+	case config.ExplainText:
+		_, err := io.WriteString(w, explainText(decision))
+		return err
+	default:
+		return fmt.Errorf("unsupported explanation format %q", format)
+	}
+}
+
+// explainText formats the decision for humans.
+func explainText(decision *processor.Decision) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Path: %s\n", decision.Path)
+	if len(decision.Paths) > 0 {
+		fmt.Fprintf(&b, "Evaluated paths: %d, including the changed files\n", len(decision.Paths))
+	}
+	fmt.Fprintf(&b, "Decision: %s (%d/%d approvals)\n", decisionSummary(decision), decision.Found, decision.Required)
+
+	b.WriteString("\nMatched rules:\n")
+	if len(decision.Sections) == 0 {
+		b.WriteString("  none, no CODEOWNERS rule matches this path\n")
+	}
+	for _, section := range decision.Sections {
+		kind := "required"
+		if section.Optional {
+			kind = "optional"
+		}
+		fmt.Fprintf(&b, "  [%s] %s (line %d), %s, %d approval(s) needed\n", section.Section, section.Pattern, section.Line, kind, section.Required)
+		fmt.Fprintf(&b, "    owners: %s\n", formatOwners(section))
+		fmt.Fprintf(&b, "    approved by: %s\n", formatList(section.Approvers))
+		if len(section.Paths) > 0 {
+			fmt.Fprintf(&b, "    paths: %s\n", strings.Join(section.Paths, ", "))
+		}
+	}
+
+	b.WriteString("\nReactions:\n")
+	if len(decision.Reactions) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, reaction := range decision.Reactions {
+		fmt.Fprintf(&b, "  %s by @%s: %s, %s\n", reactionLabel(reaction), reaction.User, reaction.Verdict, reaction.Reason)
+	}
+
+	return b.String()
+}
+
+// reactionLabel names a reaction, which is an emoji, a merge request approval
+// or an approval comment.
+func reactionLabel(reaction processor.ReactionVerdict) string {
+	switch reaction.Source {
+	case config.ApprovalSourceMergeRequest:
+		return "merge request approval"
+	case config.ApprovalSourceComment:
+		return "approval comment"
+	default:
+		return ":" + reaction.Emoji + ":"
+	}
+}
+
+// decisionSummary returns a short description of the decision outcome.
+func decisionSummary(decision *processor.Decision) string {
+	switch {
+	case len(decision.BlockedBy) > 0:
+		return "blocked by @" + strings.Join(decision.BlockedBy, ", @")
+	case decision.Approved:
+		return "approved"
+	default:
+		return "not approved"
+	}
+}
+
+// formatOwners lists the section owners, expanding group owners to their members.
+func formatOwners(section processor.SectionResult) string {
+	owners := make([]string, 0, len(section.Owners))
+	for _, owner := range section.Owners {
+		entry := "@" + owner
+		if members, ok := section.Members[owner]; ok {
+			entry += " (" + formatList(members) + ")"
+		}
+		owners = append(owners, entry)
+	}
+	return strings.Join(owners, ", ")
+}
+
+// formatList joins usernames, or returns "none" for an empty list.
+func formatList(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,98 @@
+package gate
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteExplanation(t *testing.T) {
+	decision := &processor.Decision{
+		Path:     "terraform/deploy",
+		Found:    1,
+		Required: 2,
+		Sections: []processor.SectionResult{
+			{
+				Requirement: processor.Requirement{
+					Section: "Platform",
+					Pattern: "/terraform",
+					Line:    3,
+					Owners:  []string{"alice", "platform/sre"},
+				},
+				Required:  2,
+				Members:   map[string][]string{"platform/sre": {"bob", "carol"}},
+				Approvers: []string{"alice"},
+			},
+		},
+		Reactions: []processor.ReactionVerdict{
+			{User: "alice", Emoji: "thumbsup", Verdict: processor.VerdictApproved, Reason: "counts towards Platform"},
+			{User: "dave", Emoji: "thumbsup", Verdict: processor.VerdictRejected, Reason: "not an owner of any matching section"},
+			{User: "erin", Source: config.ApprovalSourceMergeRequest, Verdict: processor.VerdictRejected, Reason: "not an owner of any matching section"},
+			{User: "frank", Source: config.ApprovalSourceComment, Verdict: processor.VerdictRejected, Reason: "comment approves docs, not terraform/deploy"},
+		},
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, WriteExplanation(&buf, decision, config.ExplainText))
+
+		out := buf.String()
+		assert.Contains(t, out, "Path: terraform/deploy")
+		assert.Contains(t, out, "Decision: not approved (1/2 approvals)")
+		assert.Contains(t, out, "[Platform] /terraform (line 3), required, 2 approval(s) needed")
+		assert.Contains(t, out, "owners: @alice, @platform/sre (bob, carol)")
+		assert.Contains(t, out, "approved by: alice")
+		assert.Contains(t, out, ":thumbsup: by @dave: rejected, not an owner of any matching section")
+		assert.Contains(t, out, "merge request approval by @erin: rejected, not an owner of any matching section")
+		assert.Contains(t, out, "approval comment by @frank: rejected, comment approves docs, not terraform/deploy")
+	})
+
+	t.Run("Text without matching rules", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, WriteExplanation(&buf, &processor.Decision{Path: "docs"}, config.ExplainText))
+		assert.Contains(t, buf.String(), "none, no CODEOWNERS rule matches this path")
+	})
+
+	t.Run("Text with changed files", func(t *testing.T) {
+		section := decision.Sections[0]
+		section.Paths = []string{"terraform/deploy", "terraform/deploy/main.tf"}
+
+		var buf bytes.Buffer
+		assert.NoError(t, WriteExplanation(&buf, &processor.Decision{
+			Path:     "terraform/deploy",
+			Paths:    []string{"terraform/deploy", "terraform/deploy/main.tf", "README.md"},
+			Sections: []processor.SectionResult{section},
+		}, config.ExplainText))
+
+		out := buf.String()
+		assert.Contains(t, out, "Evaluated paths: 3, including the changed files")
+		assert.Contains(t, out, "paths: terraform/deploy, terraform/deploy/main.tf")
+	})
+
+	t.Run("Text when blocked", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, WriteExplanation(&buf, &processor.Decision{BlockedBy: []string{"alice"}}, config.ExplainText))
+		assert.Contains(t, buf.String(), "Decision: blocked by @alice")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, WriteExplanation(&buf, decision, config.ExplainJSON))
+
+		var decoded processor.Decision
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, "terraform/deploy", decoded.Path)
+		assert.Equal(t, 3, decoded.Sections[0].Line)
+		assert.Equal(t, []string{"bob", "carol"}, decoded.Sections[0].Members["platform/sre"])
+		assert.Equal(t, processor.VerdictRejected, decoded.Reactions[1].Verdict)
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		err := WriteExplanation(&bytes.Buffer{}, decision, "yaml")
+		assert.Error(t, err)
+	})
+}
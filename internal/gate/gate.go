@@ -2,6 +2,7 @@ package gate
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -12,93 +13,377 @@ import (
 	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
 )
 
+// now returns the current time. Tests replace it to check approval ages
+// against a fixed clock.
+var now = time.Now
+
+// codeOwnersLocations lists where each provider looks for CODEOWNERS, in its
+// order of precedence. They are searched when CODEOWNERS_PATH is "auto".
+var codeOwnersLocations = map[string][]string{
+	config.ProviderGitLab:    {"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"},
+	config.ProviderGitHub:    {".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"},
+	config.ProviderGitea:     {"CODEOWNERS", "docs/CODEOWNERS", ".gitea/CODEOWNERS"},
+	config.ProviderBitbucket: {".bitbucket/CODEOWNERS"},
+}
+
+// codeOwnersPaths returns the paths to look for CODEOWNERS at, in order.
+func codeOwnersPaths(cfg config.Config) []string {
+	if cfg.CodeOwnersPath != config.CodeOwnersAuto {
+		return []string{cfg.CodeOwnersPath}
+	}
+	if locations, ok := codeOwnersLocations[cfg.Provider]; ok {
+		return locations
+	}
+	return codeOwnersLocations[config.ProviderGitLab]
+}
+
 // fetchCodeOwnersContent retrieves the CODEOWNERS file content.
 // If a separate CODEOWNERS repository is configured, it fetches from there;
-// otherwise, it fetches from the merge request's project.
-func fetchCodeOwnersContent(ctx context.Context, gc client.GitlabClientInterface, cfg config.GitlabConfig, project *client.Project) (string, error) {
+// otherwise, it fetches from the merge request's project. The ref is chosen
+// by codeOwnersRef, and the first of codeOwnersPaths that exists is used.
+func fetchCodeOwnersContent(ctx context.Context, gc client.Client, cfg config.Config, project *client.Project) (string, error) {
+	source := project
 	if cfg.CodeOwnersRepo != "" {
 		codeOwnersRepo, err := gc.GetProject(ctx, cfg.CodeOwnersRepo)
 		if err != nil {
 			return "", fmt.Errorf("failed to get codeowners project: %w", err)
 		}
-		return gc.GetFileContent(ctx, codeOwnersRepo.ID, codeOwnersRepo.DefaultBranch, cfg.CodeOwnersPath)
+		source = codeOwnersRepo
+	}
+
+	ref, err := codeOwnersRef(ctx, gc, cfg, project.ID, source)
+	if err != nil {
+		return "", err
+	}
+
+	paths := codeOwnersPaths(cfg)
+	for _, filePath := range paths {
+		content, err := gc.GetFileContent(ctx, source.ID, ref, filePath)
+		if errors.Is(err, client.ErrNotFound) {
+			slog.Debug("CODEOWNERS not found, trying the next location", "path", filePath, "ref", ref)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		slog.Info("Using CODEOWNERS file", "path", filePath, "ref", ref)
+		return content, nil
+	}
+	return "", fmt.Errorf("CODEOWNERS not found at %s on ref %s", strings.Join(paths, ", "), ref)
+}
+
+// codeOwnersRef returns the ref to read CODEOWNERS from: the default branch of
+// the repository holding it, the target branch of the merge request, or the
+// pinned ref. The configuration rejects the target branch together with a
+// separate CODEOWNERS repository, so it always names a branch of the
+// merge request's project.
+func codeOwnersRef(ctx context.Context, gc client.Client, cfg config.Config, projectID int, source *client.Project) (string, error) {
+	switch cfg.CodeOwnersRefSource {
+	case config.RefSourcePinned:
+		return cfg.CodeOwnersRef, nil
+	case config.RefSourceTargetBranch:
+		mr, err := gc.GetMergeRequest(ctx, projectID, cfg.PullRequestID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get merge request target branch: %w", err)
+		}
+		return mr.TargetBranch, nil
+	default:
+		return source.DefaultBranch, nil
 	}
-	return gc.GetFileContent(ctx, project.ID, project.DefaultBranch, cfg.CodeOwnersPath)
 }
 
-// CheckMandatoryApproval validates that a merge request has received an
-// approval emoji from a user listed in the CODEOWNERS file.
-// In restricted mode, only approvals made after the latest commit are considered.
-func CheckMandatoryApproval(ctx context.Context, gc client.GitlabClientInterface, cfg config.GitlabConfig, projectID int, codeOwnersContent string, proc processor.Processor) (bool, error) {
-	reactions, err := gc.ListAwardEmojis(ctx, projectID, cfg.PullRequestID)
+// resolveGroupOwners fetches the members of every group owning one of the
+// paths, so that reactions from direct or inherited group members can be
+// matched against group entries such as "@devops" or "@platform/sre". Every
+// owner is looked up as a group once, however many paths it owns, and owners
+// the provider finds no group for are users.
+func resolveGroupOwners(ctx context.Context, gc client.Client, rules *processor.Ruleset, paths []string, terraformPath string) error {
+	for _, filePath := range paths {
+		for _, owner := range rules.UnresolvedOwners(rules.OwnersForPath(filePath, terraformPath)) {
+			members, err := gc.ListGroupMembers(ctx, owner)
+			if errors.Is(err, client.ErrNotFound) || errors.Is(err, client.ErrUnsupported) {
+				slog.Debug("Owner is not a group, matching it as a user", "owner", owner, "reason", err)
+				rules.SetUserOwner(owner)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to fetch members of group %s: %w", owner, err)
+			}
+
+			usernames := make([]string, 0, len(members))
+			for _, member := range members {
+				usernames = append(usernames, member.Username)
+			}
+			rules.SetGroupMembers(owner, usernames)
+		}
+	}
+	return nil
+}
+
+// evaluatedPaths returns the paths whose owners must approve: the Terraform
+// path and, in changed-files mode, every file the merge request changes.
+func evaluatedPaths(ctx context.Context, gc client.Client, cfg config.Config, projectID int) ([]string, error) {
+	paths := []string{cfg.TerraformPath}
+	if !cfg.ChangedFiles {
+		return paths, nil
+	}
+
+	changed, err := gc.ListChangedFiles(ctx, projectID, cfg.PullRequestID)
 	if err != nil {
-		return false, fmt.Errorf("failed to fetch reactions: %w", err)
+		return nil, fmt.Errorf("failed to fetch changed files: %w", err)
 	}
+	slog.Debug("Evaluating ownership of changed files", "files", len(changed))
+	return append(paths, changed...), nil
+}
 
-	if len(reactions) == 0 {
-		slog.Warn("Mandatory approval not found")
-		return false, nil
+// CheckMandatoryApproval validates that a merge request has received approvals
+// from the users listed in the CODEOWNERS file, as required by every section
+// matching the Terraform path, and returns the resulting decision. Approvals
+// are collected from every configured approval source.
+// In changed-files mode, the sections matching every file changed by the merge
+// request must be approved as well. In restricted mode, only approvals made
+// after the head commit was pushed are considered, and with a maximum approval
+// age only approvals given within it. Blocking emojis stay in effect until the
+// owner removes them.
+func CheckMandatoryApproval(ctx context.Context, gc client.Client, cfg config.Config, projectID int, rules *processor.Ruleset, proc processor.Processor) (*processor.Decision, error) {
+	results, skipped, err := fetchReactions(ctx, gc, cfg, projectID)
+	if err != nil {
+		return nil, err
 	}
 
-	var lastCommitTimestamp time.Time
 	if cfg.Restricted {
-		lastCommitTimestamp, err = gc.GetLatestCommitTimestamp(ctx, projectID, cfg.PullRequestID)
+		var outdated []processor.ReactionVerdict
+		results, outdated, err = dropOutdated(ctx, gc, cfg, projectID, results)
 		if err != nil {
-			return false, fmt.Errorf("failed to fetch latest commit timestamp: %w", err)
+			return nil, err
 		}
+		skipped = append(skipped, outdated...)
 	}
 
-	for _, reaction := range reactions {
-		if cfg.Restricted && reaction.UpdatedAt.Before(lastCommitTimestamp) {
-			slog.Info("Skipping outdated approval", "user", reaction.User.Username, "updated_at", reaction.UpdatedAt)
-			continue
-		}
+	if cfg.MaxApprovalAge > 0 {
+		var expired []processor.ReactionVerdict
+		results, expired = dropExpired(cfg, results)
+		skipped = append(skipped, expired...)
+	}
+
+	reactions, rejected := combineSources(results, cfg)
+	skipped = append(skipped, rejected...)
+
+	paths, err := evaluatedPaths(ctx, gc, cfg, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveGroupOwners(ctx, gc, rules, paths, cfg.TerraformPath); err != nil {
+		return nil, err
+	}
+
+	var decision *processor.Decision
+	if cfg.ChangedFiles {
+		decision = proc.CheckApprovalForPaths(rules, paths, reactions, cfg)
+	} else {
+		decision = proc.CheckApproval(rules, reactions, cfg)
+	}
+	decision.Reactions = append(skipped, decision.Reactions...)
+	logDecision(decision)
+	return decision, nil
+}
 
-		isApproved, err := proc.CheckApproval(strings.NewReader(codeOwnersContent), reaction, cfg)
+// headPushedAt returns when the head commit of the merge request was pushed.
+// When the provider cannot tell, the check fails closed unless
+// RESTRICTED_COMMIT_DATE_FALLBACK allows falling back to the date of the
+// latest commit, which is set by its author and can be backdated.
+func headPushedAt(ctx context.Context, gc client.Client, cfg config.Config, projectID int) (time.Time, error) {
+	pushedAt, err := gc.GetLatestPushTimestamp(ctx, projectID, cfg.PullRequestID)
+	if errors.Is(err, client.ErrUnsupported) && !cfg.CommitDateFallback {
+		return time.Time{}, fmt.Errorf("failed to fetch when the head commit was pushed, set RESTRICTED_COMMIT_DATE_FALLBACK=true to trust the latest commit date instead: %w", err)
+	}
+	if errors.Is(err, client.ErrUnsupported) {
+		slog.Warn("The provider does not report when the head commit was pushed, falling back to the latest commit date", "error", err)
+		commitAt, err := gc.GetLatestCommitTimestamp(ctx, projectID, cfg.PullRequestID)
 		if err != nil {
-			return false, fmt.Errorf("error during approval check: %w", err)
+			return time.Time{}, fmt.Errorf("failed to fetch latest commit timestamp: %w", err)
 		}
+		return commitAt, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch when the head commit was pushed: %w", err)
+	}
+	return pushedAt, nil
+}
 
-		if isApproved {
-			slog.Info("Mandatory approval provided", "user", reaction.User.Username)
-			return true, nil
+// dropOutdated removes the approvals made before the head commit was pushed
+// from the reactions of every source, returning a verdict for each one
+// removed. Comparing with the push rather than the commit date means rebases
+// and force-pushes invalidate earlier approvals, even of backdated commits.
+// Blocking emojis stay in effect until the owner removes them.
+func dropOutdated(ctx context.Context, gc client.Client, cfg config.Config, projectID int, results []sourceReactions) ([]sourceReactions, []processor.ReactionVerdict, error) {
+	total := 0
+	for _, result := range results {
+		total += len(result.reactions)
+	}
+	if total == 0 {
+		return results, nil, nil
+	}
+
+	pushedAt, err := headPushedAt(ctx, gc, cfg, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, skipped := dropReactions(results, cfg, "Skipping outdated approval", func(reaction *client.AwardEmoji) string {
+		switch {
+		case reaction.UpdatedAt.IsZero():
+			return "outdated: the provider does not report when it was added, so it cannot be checked against the head commit push"
+		case reaction.UpdatedAt.Before(pushedAt):
+			return fmt.Sprintf("outdated: added at %s, before the head commit was pushed at %s", reaction.UpdatedAt.Format(time.RFC3339), pushedAt.Format(time.RFC3339))
+		}
+		return ""
+	})
+	return current, skipped, nil
+}
+
+// dropExpired removes the approvals older than MAX_APPROVAL_AGE from the
+// reactions of every source, returning a verdict for each one removed.
+// Blocking emojis never expire.
+func dropExpired(cfg config.Config, results []sourceReactions) ([]sourceReactions, []processor.ReactionVerdict) {
+	expiresBefore := now().Add(-cfg.MaxApprovalAge)
+	return dropReactions(results, cfg, "Skipping expired approval", func(reaction *client.AwardEmoji) string {
+		switch {
+		case reaction.UpdatedAt.IsZero():
+			return "expired: the provider does not report when it was added, so its age cannot be checked"
+		case reaction.UpdatedAt.Before(expiresBefore):
+			return fmt.Sprintf("expired: added at %s, more than %s ago", reaction.UpdatedAt.Format(time.RFC3339), cfg.MaxApprovalAge)
+		}
+		return ""
+	})
+}
+
+// dropReactions removes the reactions the reject function gives a reason for
+// from every source, logging the message and returning a verdict for each one
+// removed. Blocking emojis are always kept.
+func dropReactions(results []sourceReactions, cfg config.Config, message string, reject func(*client.AwardEmoji) string) ([]sourceReactions, []processor.ReactionVerdict) {
+	var skipped []processor.ReactionVerdict
+	current := make([]sourceReactions, 0, len(results))
+	for _, result := range results {
+		kept := make([]*client.AwardEmoji, 0, len(result.reactions))
+		for _, reaction := range result.reactions {
+			reason := ""
+			if !processor.IsBlockingEmoji(reaction.Name, cfg) {
+				reason = reject(reaction)
+			}
+			if reason == "" {
+				kept = append(kept, reaction)
+				continue
+			}
+
+			slog.Info(message, "user", reaction.User.Username, "updated_at", reaction.UpdatedAt, "reason", reason)
+			skipped = append(skipped, processor.ReactionVerdict{
+				User:    reaction.User.Username,
+				Emoji:   reaction.Name,
+				Source:  reaction.Source,
+				Verdict: processor.VerdictRejected,
+				Reason:  reason,
+			})
 		}
+		current = append(current, sourceReactions{source: result.source, reactions: kept})
 	}
+	return current, skipped
+}
 
-	slog.Warn("Mandatory approval not found")
-	return false, nil
+// logDecision reports how many approvals were found against how many are
+// required, along with every section still waiting for approvals and every
+// owner who blocked the apply.
+func logDecision(decision *processor.Decision) {
+	if len(decision.BlockedBy) > 0 {
+		slog.Warn("Apply blocked by code owners",
+			"blocked_by", strings.Join(decision.BlockedBy, ","),
+			"approvals", decision.Found,
+			"required", decision.Required,
+		)
+		return
+	}
+
+	if decision.Approved {
+		slog.Info("Mandatory approval provided", "approvals", decision.Found, "required", decision.Required)
+		return
+	}
+
+	if len(decision.Sections) == 0 {
+		slog.Warn("No CODEOWNERS rule matches the Terraform path")
+	}
+	for _, section := range decision.Missing() {
+		slog.Warn("Section is missing approvals",
+			"section", section.Section,
+			"pattern", section.Pattern,
+			"approvals", len(section.Approvers),
+			"required", section.Required,
+		)
+	}
+	slog.Warn("Mandatory approval not found", "approvals", decision.Found, "required", decision.Required)
 }
 
 // ProcessMR orchestrates the high-level workflow for processing a merge request.
-// It fetches the project, retrieves the CODEOWNERS file, and checks for mandatory approval.
-func ProcessMR(ctx context.Context, gc client.GitlabClientInterface, cfg config.GitlabConfig, proc processor.Processor) (bool, error) {
-	project, err := gc.GetProject(ctx, fmt.Sprintf("%s/%s", cfg.BaseRepoOwner, cfg.BaseRepoName))
+// It fetches the project, parses the CODEOWNERS file once, checks for mandatory
+// approval, and optionally reports the decision on the merge request and its head commit.
+func ProcessMR(ctx context.Context, gc client.Client, cfg config.Config, proc processor.Processor) (*processor.Decision, error) {
+	projectPath := fmt.Sprintf("%s/%s", cfg.BaseRepoOwner, cfg.BaseRepoName)
+	project, err := gc.GetProject(ctx, projectPath)
 	if err != nil {
-		return false, fmt.Errorf("failed to get project: %w", err)
+		return nil, fmt.Errorf("failed to get project %s: %w", projectPath, err)
 	}
 
 	codeOwnersContent, err := fetchCodeOwnersContent(ctx, gc, cfg, project)
 	if err != nil {
-		return false, fmt.Errorf("failed to fetch CODEOWNERS file: %w", err)
+		return nil, fmt.Errorf("failed to fetch CODEOWNERS file: %w", err)
+	}
+
+	rules, err := processor.ParseCodeOwners(strings.NewReader(codeOwnersContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CODEOWNERS file: %w", err)
+	}
+
+	decision, err := CheckMandatoryApproval(ctx, gc, cfg, project.ID, rules, proc)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.PostComment {
+		// Reporting is informational, so failing to publish must not change the decision.
+		if err := publishComment(ctx, gc, cfg, project.ID, decision); err != nil {
+			slog.Warn("Failed to publish decision comment", errorAttrs(err)...)
+		}
+	}
+
+	if cfg.CommitStatus {
+		if err := publishCommitStatus(ctx, gc, cfg, project.ID, decision); err != nil {
+			slog.Warn("Failed to publish commit status", errorAttrs(err)...)
+		}
 	}
 
-	return CheckMandatoryApproval(ctx, gc, cfg, project.ID, codeOwnersContent, proc)
+	return decision, nil
 }
 
 // Run is the primary entrypoint for the application logic.
 // It returns 0 on successful approval, 1 otherwise.
-func Run(ctx context.Context, gc client.GitlabClientInterface, cfg config.GitlabConfig, proc processor.Processor) int {
+func Run(ctx context.Context, gc client.Client, cfg config.Config, proc processor.Processor) int {
 	if cfg.Insecure {
 		slog.Warn("Insecure mode enabled: MR author can approve their own MR if they are in CODEOWNERS")
 	}
 
-	approved, err := ProcessMR(ctx, gc, cfg, proc)
+	decision, err := ProcessMR(ctx, gc, cfg, proc)
 	if err != nil {
-		slog.Error("Error processing MR", "error", err)
+		slog.Error("Error processing MR", errorAttrs(err)...)
 		return 1
 	}
 
-	if approved {
+	if cfg.Explain != "" {
+		if err := WriteExplanation(explainOutput, decision, cfg.Explain); err != nil {
+			slog.Error("Error writing decision explanation", "error", err)
+		}
+	}
+
+	if decision.Approved {
 		return 0
 	}
 	return 1
@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/shini4i/atlantis-emoji-gate/internal/client"
 	clientmocks "github.com/shini4i/atlantis-emoji-gate/internal/client/mocks"
 	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
 	procmocks "github.com/shini4i/atlantis-emoji-gate/internal/processor/mocks"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -29,6 +32,22 @@ func captureLogs(t *testing.T) (*bytes.Buffer, func()) {
 	return &buf, func() { slog.SetDefault(original) }
 }
 
+// expectUserOwners expects each owner to be looked up as a group once and
+// found to be a user.
+func expectUserOwners(mc *clientmocks.MockClient, owners ...string) {
+	for _, owner := range owners {
+		mc.EXPECT().ListGroupMembers(gomock.Any(), owner).Return(nil, fmt.Errorf("404 Group Not Found: %w", client.ErrNotFound))
+	}
+}
+
+// setNow fixes the clock of the gate for the duration of the test.
+func setNow(t *testing.T, at time.Time) {
+	t.Helper()
+	original := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = original })
+}
+
 // -------------------- Unit Tests --------------------
 
 func TestFetchCodeOwnersContent(t *testing.T) {
@@ -37,8 +56,8 @@ func TestFetchCodeOwnersContent(t *testing.T) {
 	t.Run("Success with separate CodeOwnersRepo", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-		cfg := config.GitlabConfig{
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
 			CodeOwnersRepo: "shared/codeowners",
 			CodeOwnersPath: "CODEOWNERS",
 		}
@@ -55,8 +74,8 @@ func TestFetchCodeOwnersContent(t *testing.T) {
 	t.Run("Success with same project", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-		cfg := config.GitlabConfig{CodeOwnersPath: "CODEOWNERS"}
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersPath: "CODEOWNERS"}
 		project := &client.Project{ID: 1, DefaultBranch: "main"}
 
 		mc.EXPECT().GetFileContent(ctx, 1, "main", "CODEOWNERS").Return("* @dev", nil)
@@ -69,8 +88,8 @@ func TestFetchCodeOwnersContent(t *testing.T) {
 	t.Run("Failure on GetProject", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-		cfg := config.GitlabConfig{CodeOwnersRepo: "codeowners/repo"}
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersRepo: "codeowners/repo"}
 
 		mc.EXPECT().GetProject(ctx, "codeowners/repo").Return(nil, errors.New("project not found"))
 
@@ -82,8 +101,8 @@ func TestFetchCodeOwnersContent(t *testing.T) {
 	t.Run("Failure on GetFileContent", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-		cfg := config.GitlabConfig{}
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{}
 		project := &client.Project{ID: 1, DefaultBranch: "main"}
 
 		mc.EXPECT().GetFileContent(ctx, 1, "main", "").Return("", errors.New("file not found"))
@@ -92,30 +111,277 @@ func TestFetchCodeOwnersContent(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "file not found")
 	})
+
+	t.Run("Merge request target branch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersPath: "CODEOWNERS", PullRequestID: 7, CodeOwnersRefSource: config.RefSourceTargetBranch}
+		project := &client.Project{ID: 1, DefaultBranch: "main"}
+
+		mc.EXPECT().GetMergeRequest(ctx, 1, 7).Return(&client.MergeRequest{IID: 7, TargetBranch: "release/1.x"}, nil)
+		mc.EXPECT().GetFileContent(ctx, 1, "release/1.x", "CODEOWNERS").Return("* @release", nil)
+
+		content, err := fetchCodeOwnersContent(ctx, mc, cfg, project)
+		assert.NoError(t, err)
+		assert.Equal(t, "* @release", content)
+	})
+
+	t.Run("Target branch is read from the separate CodeOwnersRepo", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			CodeOwnersRepo:      "shared/codeowners",
+			CodeOwnersPath:      "CODEOWNERS",
+			PullRequestID:       7,
+			CodeOwnersRefSource: config.RefSourceTargetBranch,
+		}
+
+		mc.EXPECT().GetProject(ctx, "shared/codeowners").Return(&client.Project{ID: 42, DefaultBranch: "develop"}, nil)
+		mc.EXPECT().GetMergeRequest(ctx, 1, 7).Return(&client.MergeRequest{IID: 7, TargetBranch: "release/1.x"}, nil)
+		mc.EXPECT().GetFileContent(ctx, 42, "release/1.x", "CODEOWNERS").Return("* @release", nil)
+
+		content, err := fetchCodeOwnersContent(ctx, mc, cfg, &client.Project{ID: 1, DefaultBranch: "main"})
+		assert.NoError(t, err)
+		assert.Equal(t, "* @release", content)
+	})
+
+	t.Run("Failure on GetMergeRequest", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersPath: "CODEOWNERS", PullRequestID: 7, CodeOwnersRefSource: config.RefSourceTargetBranch}
+
+		mc.EXPECT().GetMergeRequest(ctx, 1, 7).Return(nil, errors.New("not found"))
+
+		_, err := fetchCodeOwnersContent(ctx, mc, cfg, &client.Project{ID: 1, DefaultBranch: "main"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get merge request target branch")
+	})
+
+	t.Run("Pinned ref in the separate CodeOwnersRepo", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			CodeOwnersRepo:      "shared/codeowners",
+			CodeOwnersPath:      "CODEOWNERS",
+			CodeOwnersRefSource: config.RefSourcePinned,
+			CodeOwnersRef:       "v1.2.0",
+		}
+
+		mc.EXPECT().GetProject(ctx, "shared/codeowners").Return(&client.Project{ID: 42, DefaultBranch: "develop"}, nil)
+		mc.EXPECT().GetFileContent(ctx, 42, "v1.2.0", "CODEOWNERS").Return("* @admin", nil)
+
+		content, err := fetchCodeOwnersContent(ctx, mc, cfg, &client.Project{ID: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, "* @admin", content)
+	})
+}
+
+func TestFetchCodeOwnersContent_Discovery(t *testing.T) {
+	ctx := context.Background()
+	notFound := fmt.Errorf("received non-200 response: 404: %w", client.ErrNotFound)
+	project := &client.Project{ID: 1, DefaultBranch: "main"}
+
+	t.Run("First existing location wins", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		logs, restore := captureLogs(t)
+		defer restore()
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersPath: config.CodeOwnersAuto}
+
+		gomock.InOrder(
+			mc.EXPECT().GetFileContent(ctx, 1, "main", "CODEOWNERS").Return("", notFound),
+			mc.EXPECT().GetFileContent(ctx, 1, "main", "docs/CODEOWNERS").Return("* @docs", nil),
+		)
+
+		content, err := fetchCodeOwnersContent(ctx, mc, cfg, project)
+		assert.NoError(t, err)
+		assert.Equal(t, "* @docs", content)
+		assert.Contains(t, logs.String(), "Using CODEOWNERS file")
+		assert.Contains(t, logs.String(), "path=docs/CODEOWNERS")
+	})
+
+	t.Run("Provider locations", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersPath: config.CodeOwnersAuto, Provider: config.ProviderGitHub}
+
+		mc.EXPECT().GetFileContent(ctx, 1, "main", ".github/CODEOWNERS").Return("* @octocat", nil)
+
+		content, err := fetchCodeOwnersContent(ctx, mc, cfg, project)
+		assert.NoError(t, err)
+		assert.Equal(t, "* @octocat", content)
+	})
+
+	t.Run("No location exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersPath: config.CodeOwnersAuto}
+
+		mc.EXPECT().GetFileContent(ctx, 1, "main", gomock.Any()).Return("", notFound).Times(3)
+
+		_, err := fetchCodeOwnersContent(ctx, mc, cfg, project)
+		assert.EqualError(t, err, "CODEOWNERS not found at CODEOWNERS, docs/CODEOWNERS, .gitlab/CODEOWNERS on ref main")
+	})
+
+	t.Run("Other errors stop the search", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersPath: config.CodeOwnersAuto}
+
+		mc.EXPECT().GetFileContent(ctx, 1, "main", "CODEOWNERS").Return("", errors.New("received non-200 response: 401"))
+
+		_, err := fetchCodeOwnersContent(ctx, mc, cfg, project)
+		assert.ErrorContains(t, err, "401")
+	})
+
+	t.Run("Missing configured path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{CodeOwnersPath: "ops/CODEOWNERS", CodeOwnersRefSource: config.RefSourcePinned, CodeOwnersRef: "v1"}
+
+		mc.EXPECT().GetFileContent(ctx, 1, "v1", "ops/CODEOWNERS").Return("", notFound)
+
+		_, err := fetchCodeOwnersContent(ctx, mc, cfg, project)
+		assert.EqualError(t, err, "CODEOWNERS not found at ops/CODEOWNERS on ref v1")
+	})
+}
+
+func TestResolveGroupOwners(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Config{TerraformPath: "terraform"}
+
+	t.Run("Resolves every group owning the path once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		rules, err := processor.ParseCodeOwners(strings.NewReader(`
+			/terraform @platform/sre @alice
+			[Security]
+			/terraform @platform/sre @security/reviewers
+			/docs @docs/writers
+		`))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListGroupMembers(ctx, "platform/sre").Return([]*client.User{{Username: "bob"}}, nil)
+		mc.EXPECT().ListGroupMembers(ctx, "security/reviewers").Return([]*client.User{{Username: "carol"}}, nil)
+		expectUserOwners(mc, "alice")
+
+		assert.NoError(t, resolveGroupOwners(ctx, mc, rules, []string{cfg.TerraformPath}, cfg.TerraformPath))
+		assert.Empty(t, rules.UnresolvedOwners(rules.OwnersFor(cfg.TerraformPath)))
+	})
+
+	t.Run("Resolves the groups of every path once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		rules, err := processor.ParseCodeOwners(strings.NewReader(`
+			/terraform @platform/sre
+			/modules @platform/sre @platform/networking
+		`))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListGroupMembers(ctx, "platform/sre").Return([]*client.User{{Username: "bob"}}, nil)
+		mc.EXPECT().ListGroupMembers(ctx, "platform/networking").Return([]*client.User{{Username: "dave"}}, nil)
+
+		assert.NoError(t, resolveGroupOwners(ctx, mc, rules, []string{"terraform", "modules/vpc/main.tf"}, cfg.TerraformPath))
+		assert.Empty(t, rules.UnresolvedOwners(rules.OwnersForFile("modules/vpc/main.tf")))
+	})
+
+	t.Run("Resolves top-level groups and keeps users", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform/ @devops @alice"))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListGroupMembers(ctx, "devops").Return([]*client.User{{Username: "bob"}}, nil)
+		expectUserOwners(mc, "alice")
+
+		assert.NoError(t, resolveGroupOwners(ctx, mc, rules, []string{cfg.TerraformPath}, cfg.TerraformPath))
+		assert.Empty(t, rules.UnresolvedOwners(rules.OwnersFor(cfg.TerraformPath)))
+
+		decision := processor.NewProcessor().CheckApproval(rules, []*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "bob"}},
+		}, config.Config{TerraformPath: "terraform", ApproveEmojis: []string{"thumbsup"}})
+		assert.True(t, decision.Approved)
+	})
+
+	t.Run("Providers without groups match every owner as a user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice"))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListGroupMembers(ctx, "alice").Return(nil, client.ErrUnsupported)
+
+		assert.NoError(t, resolveGroupOwners(ctx, mc, rules, []string{cfg.TerraformPath}, cfg.TerraformPath))
+		assert.Empty(t, rules.UnresolvedOwners(rules.OwnersFor(cfg.TerraformPath)))
+	})
+
+	t.Run("Error on ListGroupMembers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @platform/sre"))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListGroupMembers(ctx, "platform/sre").Return(nil, errors.New("forbidden"))
+
+		err = resolveGroupOwners(ctx, mc, rules, []string{cfg.TerraformPath}, cfg.TerraformPath)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to fetch members of group platform/sre")
+	})
 }
 
 func TestCheckMandatoryApproval(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("Success on second reaction", func(t *testing.T) {
+	t.Run("Success passes all reactions to the processor", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
+		mc := clientmocks.NewMockClient(ctrl)
 		mp := procmocks.NewMockProcessor(ctrl)
-		cfg := config.GitlabConfig{PullRequestID: 123}
+		cfg := config.Config{PullRequestID: 123}
 		reactionValid := &client.AwardEmoji{User: client.User{Username: "approver"}}
 		reactionInvalid := &client.AwardEmoji{User: client.User{Username: "non-approver"}}
+		reactions := []*client.AwardEmoji{reactionInvalid, reactionValid}
 
-		mc.EXPECT().ListAwardEmojis(ctx, 1, 123).Return([]*client.AwardEmoji{reactionInvalid, reactionValid}, nil)
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 123).Return(reactions, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), reactions, cfg).Return(&processor.Decision{Approved: true})
 
-		gomock.InOrder(
-			mp.EXPECT().CheckApproval(gomock.Any(), reactionInvalid, cfg).Return(false, nil),
-			mp.EXPECT().CheckApproval(gomock.Any(), reactionValid, cfg).Return(true, nil),
-		)
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, mp)
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+	})
 
-		approved, err := CheckMandatoryApproval(ctx, mc, cfg, 1, "content", mp)
+	t.Run("Processor rejection is reported as missing approval", func(t *testing.T) {
+		logBuf, cleanup := captureLogs(t)
+		defer cleanup()
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mp := procmocks.NewMockProcessor(ctrl)
+		cfg := config.Config{PullRequestID: 123}
+		reactions := []*client.AwardEmoji{{User: client.User{Username: "non-approver"}}}
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 123).Return(reactions, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), reactions, cfg).Return(&processor.Decision{Required: 1})
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, mp)
 		assert.NoError(t, err)
-		assert.True(t, approved)
+		assert.False(t, decision.Approved)
+		assert.Contains(t, logBuf.String(), "Mandatory approval not found")
 	})
 
 	t.Run("Restricted mode skips old and finds new approval", func(t *testing.T) {
@@ -124,9 +390,9 @@ func TestCheckMandatoryApproval(t *testing.T) {
 
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
+		mc := clientmocks.NewMockClient(ctrl)
 		mp := procmocks.NewMockProcessor(ctrl)
-		cfg := config.GitlabConfig{PullRequestID: 123, Restricted: true}
+		cfg := config.Config{PullRequestID: 123, Restricted: true}
 		commitTime := time.Now()
 		reactionNew := &client.AwardEmoji{User: client.User{Username: "approver"}, UpdatedAt: commitTime.Add(time.Hour)}
 
@@ -134,84 +400,652 @@ func TestCheckMandatoryApproval(t *testing.T) {
 			{User: client.User{Username: "approver"}, UpdatedAt: commitTime.Add(-time.Hour)},
 			reactionNew,
 		}, nil)
-		mc.EXPECT().GetLatestCommitTimestamp(ctx, 1, 123).Return(commitTime, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 123).Return(commitTime, nil)
 
 		// Only the new reaction should reach the processor.
-		mp.EXPECT().CheckApproval(gomock.Any(), reactionNew, cfg).Return(true, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), []*client.AwardEmoji{reactionNew}, cfg).Return(&processor.Decision{Approved: true})
 
-		approved, err := CheckMandatoryApproval(ctx, mc, cfg, 1, "content", mp)
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, mp)
 		assert.NoError(t, err)
-		assert.True(t, approved)
+		assert.True(t, decision.Approved)
 		assert.Contains(t, logBuf.String(), "Skipping outdated approval")
 	})
 
-	t.Run("Empty reactions returns early", func(t *testing.T) {
+	t.Run("Expired approvals are skipped", func(t *testing.T) {
+		logBuf, cleanup := captureLogs(t)
+		defer cleanup()
+
+		current := time.Date(2024, 1, 4, 12, 0, 0, 0, time.UTC)
+		setNow(t, current)
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, ApproveEmojis: []string{"thumbsup"}, MaxApprovalAge: 72 * time.Hour, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice @bob"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice", "bob")
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}, UpdatedAt: current.Add(-96 * time.Hour)},
+		}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		if assert.Len(t, decision.Reactions, 1) {
+			assert.Equal(t, "expired: added at 2023-12-31T12:00:00Z, more than 72h0m0s ago", decision.Reactions[0].Reason)
+		}
+		assert.Contains(t, logBuf.String(), "Skipping expired approval")
+	})
+
+	t.Run("Renewed approval within the maximum age counts", func(t *testing.T) {
+		current := time.Date(2024, 1, 4, 12, 0, 0, 0, time.UTC)
+		setNow(t, current)
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, ApproveEmojis: []string{"thumbsup"}, MaxApprovalAge: 72 * time.Hour, Restricted: true, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice")
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}, UpdatedAt: current.Add(-time.Hour)},
+		}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 5).Return(current.Add(-96*time.Hour), nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+	})
+
+	t.Run("Restricted mode with only outdated approvals passes no reactions", func(t *testing.T) {
+		logBuf, cleanup := captureLogs(t)
+		defer cleanup()
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mp := procmocks.NewMockProcessor(ctrl)
+		cfg := config.Config{PullRequestID: 123, Restricted: true}
+		commitTime := time.Now()
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 123).Return([]*client.AwardEmoji{
+			{User: client.User{Username: "approver"}, UpdatedAt: commitTime.Add(-time.Hour)},
+		}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 123).Return(commitTime, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), []*client.AwardEmoji{}, cfg).Return(&processor.Decision{Required: 1})
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, mp)
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		assert.Contains(t, logBuf.String(), "Mandatory approval not found")
+		if assert.Len(t, decision.Reactions, 1) {
+			assert.Equal(t, "approver", decision.Reactions[0].User)
+			assert.Equal(t, processor.VerdictRejected, decision.Reactions[0].Verdict)
+			assert.Contains(t, decision.Reactions[0].Reason, "before the head commit was pushed")
+		}
+	})
+
+	t.Run("Changed-files mode evaluates the changed files", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mp := procmocks.NewMockProcessor(ctrl)
+		cfg := config.Config{PullRequestID: 123, TerraformPath: "terraform", ChangedFiles: true}
+		reactions := []*client.AwardEmoji{{User: client.User{Username: "approver"}}}
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 123).Return(reactions, nil)
+		mc.EXPECT().ListChangedFiles(ctx, 1, 123).Return([]string{"terraform/main.tf", "modules/vpc/main.tf"}, nil)
+		mp.EXPECT().CheckApprovalForPaths(gomock.Any(), []string{"terraform", "terraform/main.tf", "modules/vpc/main.tf"}, reactions, cfg).
+			Return(&processor.Decision{Approved: true})
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, mp)
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+	})
+
+	t.Run("Changed-files mode requires the owners of a shared module", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, ApproveEmojis: []string{"thumbsup"}, MrAuthor: "author", TerraformPath: "terraform", ChangedFiles: true}
+		rules, err := processor.ParseCodeOwners(strings.NewReader(`
+			/terraform @alice
+			/modules/vpc @bob
+		`))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice", "bob")
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}},
+		}, nil)
+		mc.EXPECT().ListChangedFiles(ctx, 1, 5).Return([]string{"terraform/main.tf", "modules/vpc/main.tf"}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, 1, decision.Found)
+		assert.Equal(t, 2, decision.Required)
+	})
+
+	t.Run("Changed-files mode limits approval comments to their paths", func(t *testing.T) {
+		cfg := config.Config{
+			PullRequestID:   5,
+			ApprovalSources: []string{config.ApprovalSourceComment},
+			ApproveCommands: []string{"/emoji-gate approve"},
+			MrAuthor:        "author",
+			TerraformPath:   "terraform/deploy",
+			ChangedFiles:    true,
+		}
+		content := `
+			/terraform/ @alice
+			/modules/ @bob
+		`
+
+		testCases := []struct {
+			name     string
+			command  string
+			approved bool
+		}{
+			{"Another owner's path does not count", "/emoji-gate approve terraform/deploy", false},
+			{"The owner's own path counts", "/emoji-gate approve modules/vpc", true},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+
+				mc := clientmocks.NewMockClient(ctrl)
+				rules, err := processor.ParseCodeOwners(strings.NewReader(content))
+				assert.NoError(t, err)
+				expectUserOwners(mc, "alice", "bob")
+
+				mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{
+					{ID: 1, Body: "/emoji-gate approve", Author: client.User{Username: "alice"}},
+					{ID: 2, Body: tc.command, Author: client.User{Username: "bob"}},
+				}, nil)
+				mc.EXPECT().ListChangedFiles(ctx, 1, 5).Return([]string{"terraform/deploy/main.tf", "modules/vpc/main.tf"}, nil)
+
+				decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+				assert.NoError(t, err)
+				assert.Equal(t, tc.approved, decision.Approved)
+			})
+		}
+	})
+
+	t.Run("Error on ListChangedFiles", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, TerraformPath: "terraform", ChangedFiles: true}
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return(nil, nil)
+		mc.EXPECT().ListChangedFiles(ctx, 1, 5).Return(nil, errors.New("api error"))
+
+		_, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, procmocks.NewMockProcessor(ctrl))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to fetch changed files")
+	})
+
+	t.Run("Merge request approvals feed the CODEOWNERS check", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:   5,
+			ApproveEmojis:   []string{"thumbsup"},
+			ApprovalSources: []string{config.ApprovalSourceEmoji, config.ApprovalSourceMergeRequest},
+			MrAuthor:        "author",
+			TerraformPath:   "terraform",
+		}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice")
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return(nil, nil)
+		mc.EXPECT().ListApprovals(ctx, 1, 5).Return([]*client.Approval{{User: client.User{Username: "alice"}}}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+	})
+
+	t.Run("Emojis named like an approval source are not approvals", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:      5,
+			ApproveEmojis:      []string{"thumbsup"},
+			ApproveEmojiScopes: map[string]string{"thumbsup": "Security"},
+			ApprovalSources:    []string{config.ApprovalSourceEmoji},
+			TerraformPath:      "terraform",
+		}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice")
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "merge_request_approval", User: client.User{Username: "alice"}},
+			{Name: "comment_approval", User: client.User{Username: "alice"}},
+		}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		if assert.Len(t, decision.Reactions, 2) {
+			assert.Equal(t, `emoji "merge_request_approval" is not an approval emoji`, decision.Reactions[0].Reason)
+		}
+	})
+
+	t.Run("Restricted mode skips approvals without a known time", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:   5,
+			ApprovalSources: []string{config.ApprovalSourceMergeRequest},
+			Restricted:      true,
+			TerraformPath:   "terraform",
+		}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice")
+
+		mc.EXPECT().ListApprovals(ctx, 1, 5).Return([]*client.Approval{{User: client.User{Username: "alice"}}}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 5).Return(time.Now(), nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		if assert.Len(t, decision.Reactions, 1) {
+			assert.Contains(t, decision.Reactions[0].Reason, "does not report when it was added")
+		}
+	})
+
+	t.Run("Restricted mode checks approval comments like reactions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:   5,
+			ApprovalSources: []string{config.ApprovalSourceComment},
+			ApproveCommands: []string{"/emoji-gate approve"},
+			Restricted:      true,
+			MrAuthor:        "author",
+			TerraformPath:   "terraform",
+		}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice @bob"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice", "bob")
+		commitTime := time.Now()
+
+		mc.EXPECT().ListNotes(ctx, 1, 5).Return([]*client.Note{
+			{Body: "/emoji-gate approve", Author: client.User{Username: "alice"}, UpdatedAt: commitTime.Add(-time.Hour)},
+			{Body: "/emoji-gate approve terraform", Author: client.User{Username: "bob"}, UpdatedAt: commitTime.Add(time.Hour)},
+		}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 5).Return(commitTime, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, []string{"bob"}, decision.Sections[0].Approvers)
+		if assert.Len(t, decision.Reactions, 2) {
+			assert.Equal(t, "alice", decision.Reactions[0].User)
+			assert.Contains(t, decision.Reactions[0].Reason, "before the head commit was pushed")
+		}
+	})
+
+	t.Run("All-of mode needs an approval through every source", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:      5,
+			ApproveEmojis:      []string{"thumbsup"},
+			ApprovalSources:    []string{config.ApprovalSourceEmoji, config.ApprovalSourceMergeRequest},
+			ApprovalSourceMode: config.SourceModeAllOf,
+			TerraformPath:      "terraform",
+		}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice @bob"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice", "bob")
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}},
+		}, nil)
+		mc.EXPECT().ListApprovals(ctx, 1, 5).Return([]*client.Approval{{User: client.User{Username: "bob"}}}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		assert.Len(t, decision.Reactions, 2)
+	})
+
+	t.Run("Group members can approve", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, ApproveEmojis: []string{"thumbsup"}, MrAuthor: "author", TerraformPath: "terraform/deploy"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @platform/sre"))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "bob"}},
+		}, nil)
+		mc.EXPECT().ListGroupMembers(ctx, "platform/sre").Return([]*client.User{{Username: "bob"}}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+	})
+
+	t.Run("Error on group resolution", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @platform/sre"))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{{User: client.User{Username: "bob"}}}, nil)
+		mc.EXPECT().ListGroupMembers(ctx, "platform/sre").Return(nil, errors.New("api error"))
+
+		_, err = CheckMandatoryApproval(ctx, mc, cfg, 1, rules, procmocks.NewMockProcessor(ctrl))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to fetch members of group")
+	})
+
+	t.Run("Owner veto blocks the apply", func(t *testing.T) {
+		logBuf, cleanup := captureLogs(t)
+		defer cleanup()
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 7, ApproveEmojis: []string{"thumbsup"}, BlockEmoji: []string{"thumbsdown"}, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice @bob"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice", "bob")
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 7).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}},
+			{Name: "thumbsdown", User: client.User{Username: "bob"}},
+		}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		assert.Contains(t, logBuf.String(), "Apply blocked by code owners")
+		assert.Contains(t, logBuf.String(), "blocked_by=bob")
+		assert.NotContains(t, logBuf.String(), "Mandatory approval provided")
+	})
+
+	t.Run("Owner veto blocks the apply without the emoji source", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:   5,
+			ApproveEmojis:   []string{"thumbsup"},
+			BlockEmoji:      []string{"thumbsdown"},
+			ApprovalSources: []string{config.ApprovalSourceMergeRequest},
+			TerraformPath:   "terraform",
+		}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice @carol"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice", "carol")
+
+		mc.EXPECT().ListApprovals(ctx, 1, 5).Return([]*client.Approval{{User: client.User{Username: "alice"}}}, nil)
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "carol"}},
+			{Name: "thumbsdown", User: client.User{Username: "carol"}},
+		}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, []string{"carol"}, decision.BlockedBy)
+		assert.Equal(t, []string{"alice"}, decision.Sections[0].Approvers)
+	})
+
+	t.Run("Restricted mode keeps outdated vetoes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mp := procmocks.NewMockProcessor(ctrl)
+		cfg := config.Config{PullRequestID: 7, Restricted: true, BlockEmoji: []string{"thumbsdown"}}
+		commitTime := time.Now()
+		veto := &client.AwardEmoji{Name: "thumbsdown", User: client.User{Username: "bob"}, UpdatedAt: commitTime.Add(-time.Hour)}
+		staleApproval := &client.AwardEmoji{Name: "thumbsup", User: client.User{Username: "alice"}, UpdatedAt: commitTime.Add(-time.Hour)}
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 7).Return([]*client.AwardEmoji{staleApproval, veto}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 7).Return(commitTime, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), []*client.AwardEmoji{veto}, cfg).Return(&processor.Decision{BlockedBy: []string{"bob"}})
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, mp)
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+	})
+
+	t.Run("Empty reactions report the required approvals", func(t *testing.T) {
 		logBuf, cleanup := captureLogs(t)
 		defer cleanup()
 
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{Restricted: true, RequiredApprovals: 2, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice @bob"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice", "bob")
+
+		// Restricted mode must not look up commits when there is nothing to filter.
 		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return([]*client.AwardEmoji{}, nil)
 
-		approved, err := CheckMandatoryApproval(ctx, mc, config.GitlabConfig{}, 1, "", procmocks.NewMockProcessor(ctrl))
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
 		assert.NoError(t, err)
-		assert.False(t, approved)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, 0, decision.Found)
+		assert.Equal(t, 2, decision.Required)
+		assert.Contains(t, logBuf.String(), "Section is missing approvals")
 		assert.Contains(t, logBuf.String(), "Mandatory approval not found")
+		assert.Contains(t, logBuf.String(), "approvals=0 required=2")
+	})
+
+	t.Run("Unmatched path is logged", func(t *testing.T) {
+		logBuf, cleanup := captureLogs(t)
+		defer cleanup()
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/docs @writer"))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return([]*client.AwardEmoji{}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		assert.Contains(t, logBuf.String(), "No CODEOWNERS rule matches the Terraform path")
+	})
+
+	t.Run("Two distinct owners are required", func(t *testing.T) {
+		logBuf, cleanup := captureLogs(t)
+		defer cleanup()
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 7, ApproveEmojis: []string{"thumbsup"}, RequiredApprovals: 2, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice @bob"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice", "bob")
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 7).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}},
+			{Name: "thumbsup", User: client.User{Username: "bob"}},
+		}, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+		assert.Contains(t, logBuf.String(), "approvals=2 required=2")
 	})
 
 	t.Run("Error on ListAwardEmojis", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
+		mc := clientmocks.NewMockClient(ctrl)
 		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return(nil, errors.New("api error"))
 
-		_, err := CheckMandatoryApproval(ctx, mc, config.GitlabConfig{}, 1, "", procmocks.NewMockProcessor(ctrl))
+		_, err := CheckMandatoryApproval(ctx, mc, config.Config{}, 1, &processor.Ruleset{}, procmocks.NewMockProcessor(ctrl))
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to fetch reactions")
 	})
 
-	t.Run("Error on GetLatestCommitTimestamp", func(t *testing.T) {
+	t.Run("Restricted mode invalidates approvals before a force-push of a backdated commit", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-		cfg := config.GitlabConfig{Restricted: true}
-		reaction := &client.AwardEmoji{User: client.User{Username: "approver"}}
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, ApproveEmojis: []string{"thumbsup"}, Restricted: true, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice")
 
-		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return([]*client.AwardEmoji{reaction}, nil)
+		// The force-pushed commit claims to be a week old, but was pushed after
+		// alice approved the previous head.
+		pushedAt := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}, UpdatedAt: pushedAt.Add(-time.Hour)},
+		}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 5).Return(pushedAt, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+		if assert.Len(t, decision.Reactions, 1) {
+			assert.Equal(t, "outdated: added at 2024-01-08T11:00:00Z, before the head commit was pushed at 2024-01-08T12:00:00Z", decision.Reactions[0].Reason)
+		}
+	})
+
+	t.Run("Restricted mode falls back to the commit date without push times", func(t *testing.T) {
+		logBuf, cleanup := captureLogs(t)
+		defer cleanup()
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, ApproveEmojis: []string{"thumbsup"}, Restricted: true, CommitDateFallback: true, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice"))
+		assert.NoError(t, err)
+		expectUserOwners(mc, "alice")
+		commitTime := time.Now()
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}, UpdatedAt: commitTime.Add(time.Minute)},
+		}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 5).Return(time.Time{}, fmt.Errorf("repository activity: %w", client.ErrUnsupported))
+		mc.EXPECT().GetLatestCommitTimestamp(ctx, 1, 5).Return(commitTime, nil)
+
+		decision, err := CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+		assert.Contains(t, logBuf.String(), "falling back to the latest commit date")
+	})
+
+	t.Run("Restricted mode refuses the commit date fallback unless allowed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{PullRequestID: 5, ApproveEmojis: []string{"thumbsup"}, Restricted: true, TerraformPath: "terraform"}
+		rules, err := processor.ParseCodeOwners(strings.NewReader("/terraform @alice"))
+		assert.NoError(t, err)
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 5).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}, UpdatedAt: time.Now()},
+		}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 5).Return(time.Time{}, fmt.Errorf("repository activity: %w", client.ErrUnsupported))
+
+		_, err = CheckMandatoryApproval(ctx, mc, cfg, 1, rules, processor.NewProcessor())
+		assert.ErrorIs(t, err, client.ErrUnsupported)
+		assert.ErrorContains(t, err, "RESTRICTED_COMMIT_DATE_FALLBACK")
+	})
+
+	t.Run("Error on GetLatestCommitTimestamp after falling back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{Restricted: true, CommitDateFallback: true}
+
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return([]*client.AwardEmoji{{User: client.User{Username: "approver"}}}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 0).Return(time.Time{}, client.ErrUnsupported)
 		mc.EXPECT().GetLatestCommitTimestamp(ctx, 1, 0).Return(time.Time{}, errors.New("commit error"))
 
-		_, err := CheckMandatoryApproval(ctx, mc, cfg, 1, "", procmocks.NewMockProcessor(ctrl))
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to fetch latest commit timestamp")
+		_, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, procmocks.NewMockProcessor(ctrl))
+		assert.ErrorContains(t, err, "failed to fetch latest commit timestamp")
 	})
 
-	t.Run("Error on CheckApproval propagates", func(t *testing.T) {
+	t.Run("Error on GetLatestPushTimestamp", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-		mp := procmocks.NewMockProcessor(ctrl)
-		cfg := config.GitlabConfig{PullRequestID: 10}
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{Restricted: true}
 		reaction := &client.AwardEmoji{User: client.User{Username: "approver"}}
 
-		mc.EXPECT().ListAwardEmojis(ctx, 1, 10).Return([]*client.AwardEmoji{reaction}, nil)
-		mp.EXPECT().CheckApproval(gomock.Any(), reaction, cfg).Return(false, errors.New("parse error"))
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return([]*client.AwardEmoji{reaction}, nil)
+		mc.EXPECT().GetLatestPushTimestamp(ctx, 1, 0).Return(time.Time{}, errors.New("commit error"))
 
-		_, err := CheckMandatoryApproval(ctx, mc, cfg, 1, "content", mp)
+		_, err := CheckMandatoryApproval(ctx, mc, cfg, 1, &processor.Ruleset{}, procmocks.NewMockProcessor(ctrl))
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "error during approval check")
-		assert.Contains(t, err.Error(), "parse error")
+		assert.Contains(t, err.Error(), "failed to fetch when the head commit was pushed")
 	})
 }
 
+func TestDropExpired(t *testing.T) {
+	current := time.Date(2024, 1, 4, 12, 0, 0, 0, time.UTC)
+	setNow(t, current)
+
+	cfg := config.Config{MaxApprovalAge: 72 * time.Hour, BlockEmoji: []string{"thumbsdown"}}
+	fresh := &client.AwardEmoji{Name: "thumbsup", User: client.User{Username: "alice"}, UpdatedAt: current.Add(-71 * time.Hour)}
+	boundary := &client.AwardEmoji{Name: "thumbsup", User: client.User{Username: "carol"}, UpdatedAt: current.Add(-72 * time.Hour)}
+	block := &client.AwardEmoji{Name: "thumbsdown", User: client.User{Username: "dave"}, UpdatedAt: current.Add(-30 * 24 * time.Hour)}
+	results := []sourceReactions{
+		{source: config.ApprovalSourceEmoji, reactions: []*client.AwardEmoji{
+			fresh,
+			{Name: "thumbsup", User: client.User{Username: "bob"}, UpdatedAt: current.Add(-73 * time.Hour)},
+			boundary,
+			block,
+		}},
+		{source: config.ApprovalSourceMergeRequest, reactions: []*client.AwardEmoji{
+			{User: client.User{Username: "erin"}, Source: config.ApprovalSourceMergeRequest},
+		}},
+	}
+
+	kept, skipped := dropExpired(cfg, results)
+	assert.Equal(t, []sourceReactions{
+		{source: config.ApprovalSourceEmoji, reactions: []*client.AwardEmoji{fresh, boundary, block}},
+		{source: config.ApprovalSourceMergeRequest, reactions: []*client.AwardEmoji{}},
+	}, kept)
+	assert.Equal(t, []processor.ReactionVerdict{
+		{User: "bob", Emoji: "thumbsup", Verdict: processor.VerdictRejected, Reason: "expired: added at 2024-01-01T11:00:00Z, more than 72h0m0s ago"},
+		{User: "erin", Source: config.ApprovalSourceMergeRequest, Verdict: processor.VerdictRejected, Reason: "expired: the provider does not report when it was added, so its age cannot be checked"},
+	}, skipped)
+}
+
 func TestProcessMR(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("Error on GetProject", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-		cfg := config.GitlabConfig{BaseRepoOwner: "org", BaseRepoName: "repo"}
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{BaseRepoOwner: "org", BaseRepoName: "repo"}
 
 		mc.EXPECT().GetProject(ctx, "org/repo").Return(nil, errors.New("not found"))
 
@@ -223,8 +1057,8 @@ func TestProcessMR(t *testing.T) {
 	t.Run("Error on fetchCodeOwnersContent", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-		cfg := config.GitlabConfig{BaseRepoOwner: "org", BaseRepoName: "repo", CodeOwnersPath: "CODEOWNERS"}
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{BaseRepoOwner: "org", BaseRepoName: "repo", CodeOwnersPath: "CODEOWNERS"}
 		project := &client.Project{ID: 1, DefaultBranch: "main"}
 
 		mc.EXPECT().GetProject(ctx, "org/repo").Return(project, nil)
@@ -234,22 +1068,146 @@ func TestProcessMR(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to fetch CODEOWNERS file")
 	})
+
+	t.Run("Error on invalid CODEOWNERS", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{BaseRepoOwner: "org", BaseRepoName: "repo", CodeOwnersPath: "CODEOWNERS"}
+		project := &client.Project{ID: 1, DefaultBranch: "main"}
+
+		mc.EXPECT().GetProject(ctx, "org/repo").Return(project, nil)
+		mc.EXPECT().GetFileContent(ctx, 1, "main", "CODEOWNERS").Return("[* @owner", nil)
+
+		_, err := ProcessMR(ctx, mc, cfg, procmocks.NewMockProcessor(ctrl))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse CODEOWNERS file")
+		assert.Contains(t, err.Error(), "invalid pattern")
+	})
+
+	t.Run("CODEOWNERS is parsed once for all reactions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mp := procmocks.NewMockProcessor(ctrl)
+		cfg := config.Config{BaseRepoOwner: "org", BaseRepoName: "repo", CodeOwnersPath: "CODEOWNERS", TerraformPath: "terraform"}
+		project := &client.Project{ID: 1, DefaultBranch: "main"}
+		reactions := []*client.AwardEmoji{
+			{User: client.User{Username: "first"}},
+			{User: client.User{Username: "second"}},
+		}
+
+		mc.EXPECT().GetProject(ctx, "org/repo").Return(project, nil)
+		mc.EXPECT().GetFileContent(ctx, 1, "main", "CODEOWNERS").Return("/terraform @first", nil)
+		expectUserOwners(mc, "first")
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return(reactions, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), reactions, cfg).DoAndReturn(
+			func(rules *processor.Ruleset, _ []*client.AwardEmoji, cfg config.Config) *processor.Decision {
+				owners := rules.OwnersFor(cfg.TerraformPath)
+				assert.Len(t, owners, 1)
+				assert.Equal(t, []string{"first"}, owners[0].Owners)
+				return &processor.Decision{Approved: true}
+			})
+
+		decision, err := ProcessMR(ctx, mc, cfg, mp)
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+	})
+	t.Run("Decision is posted as a comment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mp := procmocks.NewMockProcessor(ctrl)
+		cfg := config.Config{BaseRepoOwner: "org", BaseRepoName: "repo", CodeOwnersPath: "CODEOWNERS", PostComment: true}
+		project := &client.Project{ID: 1, DefaultBranch: "main"}
+
+		mc.EXPECT().GetProject(ctx, "org/repo").Return(project, nil)
+		mc.EXPECT().GetFileContent(ctx, 1, "main", "CODEOWNERS").Return("* @owner", nil)
+		expectUserOwners(mc, "owner")
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return(nil, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), gomock.Any(), cfg).Return(&processor.Decision{Required: 1})
+		mc.EXPECT().GetCurrentUser(ctx).Return(&client.User{Username: "bot"}, nil)
+		mc.EXPECT().ListNotes(ctx, 1, 0).Return(nil, nil)
+		mc.EXPECT().CreateNote(ctx, 1, 0, gomock.Any()).Return(&client.Note{ID: 1}, nil)
+
+		decision, err := ProcessMR(ctx, mc, cfg, mp)
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+	})
+
+	t.Run("Decision is published as a commit status", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mp := procmocks.NewMockProcessor(ctrl)
+		cfg := config.Config{
+			BaseRepoOwner:    "org",
+			BaseRepoName:     "repo",
+			CodeOwnersPath:   "CODEOWNERS",
+			TerraformPath:    "terraform",
+			CommitStatus:     true,
+			CommitStatusName: "emoji-gate",
+		}
+		project := &client.Project{ID: 1, DefaultBranch: "main"}
+
+		mc.EXPECT().GetProject(ctx, "org/repo").Return(project, nil)
+		mc.EXPECT().GetFileContent(ctx, 1, "main", "CODEOWNERS").Return("* @owner", nil)
+		expectUserOwners(mc, "owner")
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return(nil, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), gomock.Any(), cfg).Return(&processor.Decision{Required: 1, Sections: []processor.SectionResult{{Required: 1}}})
+		mc.EXPECT().GetMergeRequest(ctx, 1, 0).Return(&client.MergeRequest{SHA: "abc123"}, nil)
+		mc.EXPECT().ListCommitStatuses(ctx, 1, "abc123", "emoji-gate/terraform").Return(nil, nil)
+		mc.EXPECT().SetCommitStatus(ctx, 1, "abc123", gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ int, _ string, status client.CommitStatus) error {
+				assert.Equal(t, client.CommitStatusPending, status.State)
+				return nil
+			})
+
+		decision, err := ProcessMR(ctx, mc, cfg, mp)
+		assert.NoError(t, err)
+		assert.False(t, decision.Approved)
+	})
+
+	t.Run("Comment failures do not change the decision", func(t *testing.T) {
+		logBuf, cleanup := captureLogs(t)
+		defer cleanup()
+
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mp := procmocks.NewMockProcessor(ctrl)
+		cfg := config.Config{BaseRepoOwner: "org", BaseRepoName: "repo", CodeOwnersPath: "CODEOWNERS", PostComment: true}
+		project := &client.Project{ID: 1, DefaultBranch: "main"}
+
+		mc.EXPECT().GetProject(ctx, "org/repo").Return(project, nil)
+		mc.EXPECT().GetFileContent(ctx, 1, "main", "CODEOWNERS").Return("* @owner", nil)
+		expectUserOwners(mc, "owner")
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return(nil, nil)
+		mp.EXPECT().CheckApproval(gomock.Any(), gomock.Any(), cfg).Return(&processor.Decision{Approved: true})
+		mc.EXPECT().GetCurrentUser(ctx).Return(&client.User{Username: "bot"}, nil)
+		mc.EXPECT().ListNotes(ctx, 1, 0).Return(nil, errors.New("403 Forbidden"))
+
+		decision, err := ProcessMR(ctx, mc, cfg, mp)
+		assert.NoError(t, err)
+		assert.True(t, decision.Approved)
+		assert.Contains(t, logBuf.String(), "Failed to publish decision comment")
+	})
 }
 
 func TestRun_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	ctx := context.Background()
-	mc := clientmocks.NewMockGitlabClientInterface(ctrl)
+	mc := clientmocks.NewMockClient(ctrl)
 	mp := procmocks.NewMockProcessor(ctrl)
-	cfg := config.GitlabConfig{}
+	cfg := config.Config{}
 	project := &client.Project{ID: 1, DefaultBranch: "main"}
 	reaction := &client.AwardEmoji{User: client.User{Username: "approver"}}
 
 	mc.EXPECT().GetProject(ctx, "/").Return(project, nil)
 	mc.EXPECT().GetFileContent(ctx, 1, "main", "").Return("content", nil)
 	mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return([]*client.AwardEmoji{reaction}, nil)
-	mp.EXPECT().CheckApproval(gomock.Any(), reaction, cfg).Return(true, nil)
+	mp.EXPECT().CheckApproval(gomock.Any(), []*client.AwardEmoji{reaction}, cfg).Return(&processor.Decision{Approved: true})
 
 	exitCode := Run(ctx, mc, cfg, mp)
 	assert.Equal(t, 0, exitCode)
@@ -262,8 +1220,8 @@ func TestRun_Error(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	ctx := context.Background()
-	mc := clientmocks.NewMockGitlabClientInterface(ctrl)
-	cfg := config.GitlabConfig{}
+	mc := clientmocks.NewMockClient(ctrl)
+	cfg := config.Config{}
 
 	mc.EXPECT().GetProject(ctx, "/").Return(nil, errors.New("gitlab is down"))
 
@@ -281,16 +1239,16 @@ func TestRun_NotApproved(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	ctx := context.Background()
-	mc := clientmocks.NewMockGitlabClientInterface(ctrl)
+	mc := clientmocks.NewMockClient(ctrl)
 	mp := procmocks.NewMockProcessor(ctrl)
-	cfg := config.GitlabConfig{}
+	cfg := config.Config{}
 	project := &client.Project{ID: 1, DefaultBranch: "main"}
 	reaction := &client.AwardEmoji{User: client.User{Username: "someone"}}
 
 	mc.EXPECT().GetProject(ctx, "/").Return(project, nil)
 	mc.EXPECT().GetFileContent(ctx, 1, "main", "").Return("content", nil)
 	mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return([]*client.AwardEmoji{reaction}, nil)
-	mp.EXPECT().CheckApproval(gomock.Any(), reaction, cfg).Return(false, nil)
+	mp.EXPECT().CheckApproval(gomock.Any(), []*client.AwardEmoji{reaction}, cfg).Return(&processor.Decision{Required: 1})
 
 	exitCode := Run(ctx, mc, cfg, mp)
 	assert.Equal(t, 1, exitCode)
@@ -304,16 +1262,16 @@ func TestRun_InsecureMode(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	ctx := context.Background()
-	mc := clientmocks.NewMockGitlabClientInterface(ctrl)
+	mc := clientmocks.NewMockClient(ctrl)
 	mp := procmocks.NewMockProcessor(ctrl)
-	cfg := config.GitlabConfig{Insecure: true}
+	cfg := config.Config{Insecure: true}
 	project := &client.Project{ID: 1, DefaultBranch: "main"}
 	reaction := &client.AwardEmoji{User: client.User{Username: "approver"}}
 
 	mc.EXPECT().GetProject(ctx, "/").Return(project, nil)
 	mc.EXPECT().GetFileContent(ctx, 1, "main", "").Return("content", nil)
 	mc.EXPECT().ListAwardEmojis(ctx, 1, 0).Return([]*client.AwardEmoji{reaction}, nil)
-	mp.EXPECT().CheckApproval(gomock.Any(), reaction, cfg).Return(true, nil)
+	mp.EXPECT().CheckApproval(gomock.Any(), []*client.AwardEmoji{reaction}, cfg).Return(&processor.Decision{Approved: true})
 
 	exitCode := Run(ctx, mc, cfg, mp)
 	assert.Equal(t, 0, exitCode)
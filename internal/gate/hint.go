@@ -0,0 +1,46 @@
+package gate
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+)
+
+// apiErrorHint turns a provider API error into a suggestion of what to fix,
+// or returns an empty string for errors it cannot say anything about.
+func apiErrorHint(err error) string {
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+
+	switch status := apiErr.StatusCode; {
+	case status == http.StatusUnauthorized:
+		return "the token is invalid, expired or revoked"
+	case status == http.StatusForbidden && strings.Contains(apiErr.Message, "insufficient_scope"):
+		return "the token lacks a required scope: read_api is needed to evaluate approvals, and api to post comments or commit statuses"
+	case status == http.StatusForbidden:
+		return fmt.Sprintf("the token is not allowed to %s %s, check the role of its user in the project", apiErr.Method, apiErr.Endpoint)
+	case status == http.StatusNotFound:
+		return fmt.Sprintf("%s was not found, check the repository, merge request and paths, and that the token can see the repository", apiErr.Endpoint)
+	case status == http.StatusTooManyRequests:
+		return "the API rate limit was hit and retries ran out, consider raising RETRY_BUDGET"
+	case status >= http.StatusInternalServerError:
+		return "the provider API failed, try again later"
+	default:
+		return ""
+	}
+}
+
+// errorAttrs returns the log attributes for an error, including a hint on how
+// to fix it when one is known.
+func errorAttrs(err error) []any {
+	attrs := []any{"error", err}
+	if hint := apiErrorHint(err); hint != "" {
+		attrs = append(attrs, "hint", hint)
+	}
+	return attrs
+}
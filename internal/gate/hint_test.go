@@ -0,0 +1,84 @@
+package gate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	clientmocks "github.com/shini4i/atlantis-emoji-gate/internal/client/mocks"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	procmocks "github.com/shini4i/atlantis-emoji-gate/internal/processor/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAPIErrorHint(t *testing.T) {
+	apiError := func(status int, method, message string) error {
+		return fmt.Errorf("failed to fetch reactions: %w", &client.APIError{
+			StatusCode: status,
+			Method:     method,
+			Endpoint:   "/api/v4/projects/1/merge_requests/2/award_emoji",
+			Message:    message,
+		})
+	}
+
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Unauthorized", apiError(http.StatusUnauthorized, http.MethodGet, "401 Unauthorized"), "the token is invalid, expired or revoked"},
+		{
+			"Insufficient scope",
+			apiError(http.StatusForbidden, http.MethodGet, "insufficient_scope: The request requires higher privileges (required scope: api read_api)"),
+			"the token lacks a required scope: read_api is needed to evaluate approvals, and api to post comments or commit statuses",
+		},
+		{
+			"Forbidden",
+			apiError(http.StatusForbidden, http.MethodPost, "403 Forbidden"),
+			"the token is not allowed to POST /api/v4/projects/1/merge_requests/2/award_emoji, check the role of its user in the project",
+		},
+		{
+			"Not found",
+			apiError(http.StatusNotFound, http.MethodGet, "404 Not Found"),
+			"/api/v4/projects/1/merge_requests/2/award_emoji was not found, check the repository, merge request and paths, and that the token can see the repository",
+		},
+		{"Rate limited", apiError(http.StatusTooManyRequests, http.MethodGet, ""), "the API rate limit was hit and retries ran out, consider raising RETRY_BUDGET"},
+		{"Server error", apiError(http.StatusBadGateway, http.MethodGet, "502 Bad Gateway"), "the provider API failed, try again later"},
+		{"Other status", apiError(http.StatusConflict, http.MethodPost, "conflict"), ""},
+		{"Not an API error", errors.New("connection refused"), ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, apiErrorHint(tc.err))
+		})
+	}
+}
+
+func TestRun_ErrorHint(t *testing.T) {
+	logBuf, cleanup := captureLogs(t)
+	defer cleanup()
+
+	ctrl := gomock.NewController(t)
+
+	ctx := context.Background()
+	mc := clientmocks.NewMockClient(ctrl)
+	cfg := config.Config{BaseRepoOwner: "infra", BaseRepoName: "terraform"}
+
+	mc.EXPECT().GetProject(ctx, "infra/terraform").Return(nil, &client.APIError{
+		StatusCode: http.StatusUnauthorized,
+		Method:     http.MethodGet,
+		Endpoint:   "/api/v4/projects/infra%2Fterraform",
+		Message:    "401 Unauthorized",
+	})
+
+	exitCode := Run(ctx, mc, cfg, procmocks.NewMockProcessor(ctrl))
+
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, logBuf.String(), "failed to get project infra/terraform")
+	assert.Contains(t, logBuf.String(), `hint="the token is invalid, expired or revoked"`)
+}
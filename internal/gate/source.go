@@ -0,0 +1,237 @@
+package gate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
+)
+
+// approvalSource provides the approvals of a merge request as reactions, so
+// that every source feeds the same CODEOWNERS check.
+type approvalSource interface {
+	// name identifies the source in the configuration and in verdicts.
+	name() string
+	// reactions returns the reactions of the source, along with a verdict for
+	// every candidate the source itself rejected.
+	reactions(ctx context.Context, gc client.Client, cfg config.Config, projectID int) ([]*client.AwardEmoji, []processor.ReactionVerdict, error)
+}
+
+// emojiSource provides the emoji reactions on the merge request, including
+// blocking emojis.
+type emojiSource struct{}
+
+func (emojiSource) name() string { return config.ApprovalSourceEmoji }
+
+func (emojiSource) reactions(ctx context.Context, gc client.Client, cfg config.Config, projectID int) ([]*client.AwardEmoji, []processor.ReactionVerdict, error) {
+	reactions, err := gc.ListAwardEmojis(ctx, projectID, cfg.PullRequestID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch reactions: %w", err)
+	}
+	return reactions, nil, nil
+}
+
+// blockingEmojiSource provides only the blocking emojis among the reactions on
+// the merge request. It is used when emoji reactions are not an approval
+// source, so that code owners can still veto the apply.
+type blockingEmojiSource struct{}
+
+func (blockingEmojiSource) name() string { return config.ApprovalSourceEmoji }
+
+func (blockingEmojiSource) reactions(ctx context.Context, gc client.Client, cfg config.Config, projectID int) ([]*client.AwardEmoji, []processor.ReactionVerdict, error) {
+	reactions, _, err := emojiSource{}.reactions(ctx, gc, cfg, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var blocking []*client.AwardEmoji
+	for _, reaction := range reactions {
+		if processor.IsBlockingEmoji(reaction.Name, cfg) {
+			blocking = append(blocking, reaction)
+		}
+	}
+	return blocking, nil, nil
+}
+
+// mergeRequestApprovalSource provides the approvals made with the provider's
+// own approve feature, reported as reactions of that source.
+type mergeRequestApprovalSource struct{}
+
+func (mergeRequestApprovalSource) name() string { return config.ApprovalSourceMergeRequest }
+
+func (mergeRequestApprovalSource) reactions(ctx context.Context, gc client.Client, cfg config.Config, projectID int) ([]*client.AwardEmoji, []processor.ReactionVerdict, error) {
+	approvals, err := gc.ListApprovals(ctx, projectID, cfg.PullRequestID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch merge request approvals: %w", err)
+	}
+
+	reactions := make([]*client.AwardEmoji, 0, len(approvals))
+	for _, approval := range approvals {
+		reactions = append(reactions, &client.AwardEmoji{
+			User:      approval.User,
+			UpdatedAt: approval.ApprovedAt,
+			Source:    config.ApprovalSourceMergeRequest,
+		})
+	}
+	return reactions, nil, nil
+}
+
+// commentSource provides the notes on the merge request that contain an
+// approval command, reported as reactions of that source and limited to the
+// command's paths, which the processor applies per section. Outside of
+// changed-files mode, commands limited to other directories than the
+// Terraform path are rejected right away.
+type commentSource struct{}
+
+func (commentSource) name() string { return config.ApprovalSourceComment }
+
+func (commentSource) reactions(ctx context.Context, gc client.Client, cfg config.Config, projectID int) ([]*client.AwardEmoji, []processor.ReactionVerdict, error) {
+	notes, err := gc.ListNotes(ctx, projectID, cfg.PullRequestID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch merge request notes: %w", err)
+	}
+
+	var reactions []*client.AwardEmoji
+	var rejected []processor.ReactionVerdict
+	for _, note := range notes {
+		if note.System {
+			continue
+		}
+		command, ok := parseApprovalCommand(note.Body, cfg.ApproveCommands)
+		if !ok {
+			continue
+		}
+		if !cfg.ChangedFiles && !command.covers(cfg.TerraformPath) {
+			rejected = append(rejected, processor.ReactionVerdict{
+				User:    note.Author.Username,
+				Source:  config.ApprovalSourceComment,
+				Verdict: processor.VerdictRejected,
+				Reason:  fmt.Sprintf("comment approves %s, not %s", strings.Join(command.paths, ", "), cfg.TerraformPath),
+			})
+			continue
+		}
+
+		slog.Debug("Found approval comment", "user", note.Author.Username, "note_id", note.ID, "reason", command.reason)
+		reactions = append(reactions, &client.AwardEmoji{
+			User:      note.Author,
+			UpdatedAt: note.UpdatedAt,
+			Source:    config.ApprovalSourceComment,
+			Paths:     command.paths,
+		})
+	}
+	return reactions, rejected, nil
+}
+
+// approvalSources returns the configured approval sources, defaulting to
+// emoji reactions. Without emoji reactions among them, blocking emojis are
+// still read when any are configured.
+func approvalSources(cfg config.Config) []approvalSource {
+	var sources []approvalSource
+	hasEmoji := false
+	for _, source := range cfg.ApprovalSources {
+		switch source {
+		case config.ApprovalSourceEmoji:
+			sources = append(sources, emojiSource{})
+			hasEmoji = true
+		case config.ApprovalSourceMergeRequest:
+			sources = append(sources, mergeRequestApprovalSource{})
+		case config.ApprovalSourceComment:
+			sources = append(sources, commentSource{})
+		}
+	}
+	switch {
+	case len(sources) == 0:
+		sources = append(sources, emojiSource{})
+	case !hasEmoji && len(cfg.BlockEmoji) > 0:
+		sources = append(sources, blockingEmojiSource{})
+	}
+	return sources
+}
+
+// sourceReactions holds the reactions provided by a single approval source.
+// blockingOnly is set for the blocking emojis read without emoji reactions
+// being an approval source, which the all-of mode does not require.
+type sourceReactions struct {
+	source       string
+	reactions    []*client.AwardEmoji
+	blockingOnly bool
+}
+
+// fetchReactions collects the reactions of every configured approval source,
+// along with the verdicts of the candidates the sources rejected.
+func fetchReactions(ctx context.Context, gc client.Client, cfg config.Config, projectID int) ([]sourceReactions, []processor.ReactionVerdict, error) {
+	sources := approvalSources(cfg)
+	results := make([]sourceReactions, 0, len(sources))
+	var rejected []processor.ReactionVerdict
+	for _, source := range sources {
+		reactions, sourceRejected, err := source.reactions(ctx, gc, cfg, projectID)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, blockingOnly := source.(blockingEmojiSource)
+		results = append(results, sourceReactions{source: source.name(), reactions: reactions, blockingOnly: blockingOnly})
+		rejected = append(rejected, sourceRejected...)
+	}
+	return results, rejected, nil
+}
+
+// combineSources merges the reactions of every source. With the any-of mode
+// an approval through any source counts. With the all-of mode a user's
+// approvals only count if the user approved through every source, and their
+// other approvals are rejected. Reactions that are not approvals, such as
+// blocking emojis, are always kept.
+func combineSources(results []sourceReactions, cfg config.Config) ([]*client.AwardEmoji, []processor.ReactionVerdict) {
+	reactions := make([]*client.AwardEmoji, 0)
+	sources := 0
+	for _, result := range results {
+		reactions = append(reactions, result.reactions...)
+		if !result.blockingOnly {
+			sources++
+		}
+	}
+	if cfg.ApprovalSourceMode != config.SourceModeAllOf || sources < 2 {
+		return reactions, nil
+	}
+
+	approvedThrough := make(map[string][]string)
+	for _, result := range results {
+		for _, reaction := range result.reactions {
+			username := reaction.User.Username
+			if processor.IsApproval(reaction, cfg) && !slices.Contains(approvedThrough[username], result.source) {
+				approvedThrough[username] = append(approvedThrough[username], result.source)
+			}
+		}
+	}
+
+	var rejected []processor.ReactionVerdict
+	combined := make([]*client.AwardEmoji, 0, len(reactions))
+	for _, result := range results {
+		for _, reaction := range result.reactions {
+			username := reaction.User.Username
+			if !processor.IsApproval(reaction, cfg) || len(approvedThrough[username]) == sources {
+				combined = append(combined, reaction)
+				continue
+			}
+
+			var missing []string
+			for _, other := range results {
+				if !other.blockingOnly && !slices.Contains(approvedThrough[username], other.source) {
+					missing = append(missing, other.source)
+				}
+			}
+			rejected = append(rejected, processor.ReactionVerdict{
+				User:    username,
+				Emoji:   reaction.Name,
+				Source:  reaction.Source,
+				Verdict: processor.VerdictRejected,
+				Reason:  fmt.Sprintf("approvals must come from every source, but none came from %s", strings.Join(missing, ", ")),
+			})
+		}
+	}
+	return combined, rejected
+}
@@ -0,0 +1,217 @@
+package gate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	clientmocks "github.com/shini4i/atlantis-emoji-gate/internal/client/mocks"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestApprovalSources(t *testing.T) {
+	assert.Equal(t, []approvalSource{emojiSource{}}, approvalSources(config.Config{}))
+	assert.Equal(t, []approvalSource{mergeRequestApprovalSource{}, emojiSource{}, commentSource{}}, approvalSources(config.Config{
+		ApprovalSources: []string{config.ApprovalSourceMergeRequest, config.ApprovalSourceEmoji, config.ApprovalSourceComment},
+	}))
+	assert.Equal(t, []approvalSource{mergeRequestApprovalSource{}, blockingEmojiSource{}}, approvalSources(config.Config{
+		ApprovalSources: []string{config.ApprovalSourceMergeRequest},
+		BlockEmoji:      []string{"thumbsdown"},
+	}))
+	assert.Equal(t, []approvalSource{emojiSource{}}, approvalSources(config.Config{
+		ApprovalSources: []string{config.ApprovalSourceEmoji},
+		BlockEmoji:      []string{"thumbsdown"},
+	}))
+}
+
+func TestFetchReactions(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Config{PullRequestID: 7, ApprovalSources: []string{config.ApprovalSourceEmoji, config.ApprovalSourceMergeRequest}}
+	approvedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Merge request approvals are reported as reactions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		emoji := &client.AwardEmoji{Name: "thumbsup", User: client.User{Username: "alice"}}
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 7).Return([]*client.AwardEmoji{emoji}, nil)
+		mc.EXPECT().ListApprovals(ctx, 1, 7).Return([]*client.Approval{{User: client.User{Username: "bob"}, ApprovedAt: approvedAt}}, nil)
+
+		results, rejected, err := fetchReactions(ctx, mc, cfg, 1)
+		assert.NoError(t, err)
+		assert.Empty(t, rejected)
+		assert.Equal(t, []sourceReactions{
+			{source: config.ApprovalSourceEmoji, reactions: []*client.AwardEmoji{emoji}},
+			{source: config.ApprovalSourceMergeRequest, reactions: []*client.AwardEmoji{
+				{User: client.User{Username: "bob"}, UpdatedAt: approvedAt, Source: config.ApprovalSourceMergeRequest},
+			}},
+		}, results)
+	})
+
+	t.Run("Only blocking emojis are read without the emoji source", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:   7,
+			ApproveEmojis:   []string{"thumbsup"},
+			BlockEmoji:      []string{"thumbsdown"},
+			ApprovalSources: []string{config.ApprovalSourceMergeRequest},
+		}
+		veto := &client.AwardEmoji{Name: "thumbsdown", User: client.User{Username: "carol"}}
+		mc.EXPECT().ListApprovals(ctx, 1, 7).Return(nil, nil)
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 7).Return([]*client.AwardEmoji{
+			{Name: "thumbsup", User: client.User{Username: "alice"}},
+			veto,
+		}, nil)
+
+		results, _, err := fetchReactions(ctx, mc, cfg, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []sourceReactions{
+			{source: config.ApprovalSourceMergeRequest, reactions: []*client.AwardEmoji{}},
+			{source: config.ApprovalSourceEmoji, reactions: []*client.AwardEmoji{veto}, blockingOnly: true},
+		}, results)
+	})
+
+	t.Run("Error on ListApprovals", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mc.EXPECT().ListAwardEmojis(ctx, 1, 7).Return(nil, nil)
+		mc.EXPECT().ListApprovals(ctx, 1, 7).Return(nil, errors.New("forbidden"))
+
+		_, _, err := fetchReactions(ctx, mc, cfg, 1)
+		assert.ErrorContains(t, err, "failed to fetch merge request approvals")
+	})
+
+	t.Run("Approval comments are reported as reactions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:   7,
+			TerraformPath:   "terraform/deploy",
+			ApprovalSources: []string{config.ApprovalSourceComment},
+			ApproveCommands: []string{"/emoji-gate approve"},
+		}
+		mc.EXPECT().ListNotes(ctx, 1, 7).Return([]*client.Note{
+			{ID: 1, Body: "/emoji-gate approve terraform/deploy\nPlan reviewed.", Author: client.User{Username: "alice"}, UpdatedAt: approvedAt},
+			{ID: 2, Body: "/emoji-gate approve terraform/provision", Author: client.User{Username: "bob"}, UpdatedAt: approvedAt},
+			{ID: 3, Body: "looks good", Author: client.User{Username: "carol"}, UpdatedAt: approvedAt},
+			{ID: 4, Body: "/emoji-gate approve", Author: client.User{Username: "gitlab"}, System: true},
+		}, nil)
+
+		results, rejected, err := fetchReactions(ctx, mc, cfg, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []sourceReactions{
+			{source: config.ApprovalSourceComment, reactions: []*client.AwardEmoji{
+				{User: client.User{Username: "alice"}, UpdatedAt: approvedAt, Source: config.ApprovalSourceComment, Paths: []string{"terraform/deploy"}},
+			}},
+		}, results)
+		assert.Equal(t, []processor.ReactionVerdict{{
+			User:    "bob",
+			Source:  config.ApprovalSourceComment,
+			Verdict: processor.VerdictRejected,
+			Reason:  "comment approves terraform/provision, not terraform/deploy",
+		}}, rejected)
+	})
+
+	t.Run("Approval comments for other paths are kept in changed-files mode", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		cfg := config.Config{
+			PullRequestID:   7,
+			TerraformPath:   "terraform/deploy",
+			ChangedFiles:    true,
+			ApprovalSources: []string{config.ApprovalSourceComment},
+			ApproveCommands: []string{"/emoji-gate approve"},
+		}
+		mc.EXPECT().ListNotes(ctx, 1, 7).Return([]*client.Note{
+			{ID: 1, Body: "/emoji-gate approve modules/vpc", Author: client.User{Username: "bob"}, UpdatedAt: approvedAt},
+		}, nil)
+
+		results, rejected, err := fetchReactions(ctx, mc, cfg, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []sourceReactions{
+			{source: config.ApprovalSourceComment, reactions: []*client.AwardEmoji{
+				{User: client.User{Username: "bob"}, UpdatedAt: approvedAt, Source: config.ApprovalSourceComment, Paths: []string{"modules/vpc"}},
+			}},
+		}, results)
+		assert.Empty(t, rejected)
+	})
+
+	t.Run("Error on ListNotes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		mc := clientmocks.NewMockClient(ctrl)
+		mc.EXPECT().ListNotes(ctx, 1, 7).Return(nil, errors.New("forbidden"))
+
+		_, _, err := fetchReactions(ctx, mc, config.Config{PullRequestID: 7, ApprovalSources: []string{config.ApprovalSourceComment}}, 1)
+		assert.ErrorContains(t, err, "failed to fetch merge request notes")
+	})
+}
+
+func TestCombineSources(t *testing.T) {
+	reaction := func(name, username string) *client.AwardEmoji {
+		return &client.AwardEmoji{Name: name, User: client.User{Username: username}}
+	}
+
+	aliceEmoji := reaction("thumbsup", "alice")
+	approval := func(username string) *client.AwardEmoji {
+		return &client.AwardEmoji{User: client.User{Username: username}, Source: config.ApprovalSourceMergeRequest}
+	}
+
+	aliceApproval := approval("alice")
+	bobEmoji := reaction("thumbsup", "bob")
+	carolApproval := approval("carol")
+	daveVeto := reaction("thumbsdown", "dave")
+	results := []sourceReactions{
+		{source: config.ApprovalSourceEmoji, reactions: []*client.AwardEmoji{aliceEmoji, bobEmoji, daveVeto}},
+		{source: config.ApprovalSourceMergeRequest, reactions: []*client.AwardEmoji{aliceApproval, carolApproval}},
+	}
+
+	t.Run("Any of the sources", func(t *testing.T) {
+		cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, ApprovalSourceMode: config.SourceModeAnyOf}
+
+		reactions, rejected := combineSources(results, cfg)
+		assert.Equal(t, []*client.AwardEmoji{aliceEmoji, bobEmoji, daveVeto, aliceApproval, carolApproval}, reactions)
+		assert.Empty(t, rejected)
+	})
+
+	t.Run("All of the sources", func(t *testing.T) {
+		cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, ApprovalSourceMode: config.SourceModeAllOf}
+
+		reactions, rejected := combineSources(results, cfg)
+		assert.Equal(t, []*client.AwardEmoji{aliceEmoji, daveVeto, aliceApproval}, reactions)
+		assert.Equal(t, []processor.ReactionVerdict{
+			{User: "bob", Emoji: "thumbsup", Verdict: processor.VerdictRejected, Reason: "approvals must come from every source, but none came from approvals"},
+			{User: "carol", Source: config.ApprovalSourceMergeRequest, Verdict: processor.VerdictRejected, Reason: "approvals must come from every source, but none came from emoji"},
+		}, rejected)
+	})
+
+	t.Run("All of the sources does not require blocking emojis", func(t *testing.T) {
+		cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, BlockEmoji: []string{"thumbsdown"}, ApprovalSourceMode: config.SourceModeAllOf}
+		vetoes := []sourceReactions{
+			results[1],
+			{source: config.ApprovalSourceEmoji, reactions: []*client.AwardEmoji{daveVeto}, blockingOnly: true},
+		}
+
+		reactions, rejected := combineSources(vetoes, cfg)
+		assert.Equal(t, []*client.AwardEmoji{aliceApproval, carolApproval, daveVeto}, reactions)
+		assert.Empty(t, rejected)
+	})
+
+	t.Run("All of a single source", func(t *testing.T) {
+		cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, ApprovalSourceMode: config.SourceModeAllOf}
+
+		reactions, rejected := combineSources(results[:1], cfg)
+		assert.Equal(t, []*client.AwardEmoji{aliceEmoji, bobEmoji, daveVeto}, reactions)
+		assert.Empty(t, rejected)
+	})
+}
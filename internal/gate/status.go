@@ -0,0 +1,68 @@
+package gate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
+)
+
+// commitStatusName returns the name of the commit status for the Terraform
+// path, e.g. "emoji-gate/terraform/deploy", so every directory gets its own status.
+func commitStatusName(cfg config.Config) string {
+	return path.Join(cfg.CommitStatusName, cfg.TerraformPath)
+}
+
+// commitStatusFor maps a decision onto a commit status. A decision still
+// waiting for approvals is pending, while a vetoed apply or a path nobody owns
+// cannot be approved by adding reactions and is reported as failed.
+func commitStatusFor(decision *processor.Decision) (state, description string) {
+	switch {
+	case len(decision.BlockedBy) > 0:
+		return client.CommitStatusFailed, fmt.Sprintf("Apply vetoed by %d code owner(s)", len(decision.BlockedBy))
+	case decision.Approved:
+		return client.CommitStatusSuccess, fmt.Sprintf("Approved by code owners (%d/%d)", decision.Found, decision.Required)
+	case len(decision.Sections) == 0:
+		return client.CommitStatusFailed, "No CODEOWNERS rule matches this directory"
+	default:
+		return client.CommitStatusPending, fmt.Sprintf("Waiting for code owner approval (%d/%d)", decision.Found, decision.Required)
+	}
+}
+
+// publishCommitStatus sets a commit status reflecting the decision on the
+// head commit of the merge request. GitLab rejects a transition to the state
+// a status already has, so an unchanged status is left alone.
+func publishCommitStatus(ctx context.Context, gc client.Client, cfg config.Config, projectID int, decision *processor.Decision) error {
+	mr, err := gc.GetMergeRequest(ctx, projectID, cfg.PullRequestID)
+	if err != nil {
+		return fmt.Errorf("failed to get merge request: %w", err)
+	}
+
+	state, description := commitStatusFor(decision)
+	status := client.CommitStatus{
+		Name:        commitStatusName(cfg),
+		State:       state,
+		Description: description,
+		TargetURL:   mr.WebURL,
+	}
+
+	existing, err := gc.ListCommitStatuses(ctx, projectID, mr.SHA, status.Name)
+	if err != nil {
+		return fmt.Errorf("failed to list commit statuses: %w", err)
+	}
+	// Providers return the most recent status first.
+	if len(existing) > 0 && existing[0].State == status.State && existing[0].Description == status.Description {
+		slog.Debug("Commit status is up to date", "name", status.Name, "state", status.State)
+		return nil
+	}
+
+	if err := gc.SetCommitStatus(ctx, projectID, mr.SHA, status); err != nil {
+		return fmt.Errorf("failed to set commit status: %w", err)
+	}
+	slog.Info("Published commit status", "name", status.Name, "state", status.State, "sha", mr.SHA)
+	return nil
+}
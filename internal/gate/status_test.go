@@ -0,0 +1,121 @@
+package gate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	clientmocks "github.com/shini4i/atlantis-emoji-gate/internal/client/mocks"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/shini4i/atlantis-emoji-gate/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCommitStatusName(t *testing.T) {
+	assert.Equal(t, "emoji-gate/terraform/deploy", commitStatusName(config.Config{CommitStatusName: "emoji-gate", TerraformPath: "terraform/deploy"}))
+	assert.Equal(t, "emoji-gate", commitStatusName(config.Config{CommitStatusName: "emoji-gate", TerraformPath: "."}))
+}
+
+func TestCommitStatusFor(t *testing.T) {
+	owned := []processor.SectionResult{{Required: 1}}
+
+	testCases := []struct {
+		name      string
+		decision  *processor.Decision
+		wantState string
+	}{
+		{"Approved", &processor.Decision{Approved: true, Found: 1, Required: 1, Sections: owned}, client.CommitStatusSuccess},
+		{"Waiting for approvals", &processor.Decision{Required: 1, Sections: owned}, client.CommitStatusPending},
+		{"Vetoed", &processor.Decision{BlockedBy: []string{"alice"}, Sections: owned}, client.CommitStatusFailed},
+		{"Unowned path", &processor.Decision{}, client.CommitStatusFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state, description := commitStatusFor(tc.decision)
+			assert.Equal(t, tc.wantState, state)
+			assert.NotEmpty(t, description)
+		})
+	}
+}
+
+func TestPublishCommitStatus(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Config{PullRequestID: 5, TerraformPath: "terraform", CommitStatusName: "emoji-gate"}
+	decision := &processor.Decision{Approved: true, Found: 1, Required: 1, Sections: []processor.SectionResult{{Required: 1}}}
+	mr := &client.MergeRequest{SHA: "abc123", WebURL: "https://gitlab.example.com/mr/5"}
+	want := client.CommitStatus{
+		Name:        "emoji-gate/terraform",
+		State:       client.CommitStatusSuccess,
+		Description: "Approved by code owners (1/1)",
+		TargetURL:   mr.WebURL,
+	}
+
+	t.Run("Sets the status on the head commit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mc := clientmocks.NewMockClient(ctrl)
+
+		mc.EXPECT().GetMergeRequest(ctx, 1, 5).Return(mr, nil)
+		mc.EXPECT().ListCommitStatuses(ctx, 1, "abc123", "emoji-gate/terraform").Return([]*client.CommitStatus{
+			{Name: "emoji-gate/terraform", State: client.CommitStatusPending},
+		}, nil)
+		mc.EXPECT().SetCommitStatus(ctx, 1, "abc123", want).Return(nil)
+
+		assert.NoError(t, publishCommitStatus(ctx, mc, cfg, 1, decision))
+	})
+
+	t.Run("Leaves an unchanged status alone", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mc := clientmocks.NewMockClient(ctrl)
+
+		mc.EXPECT().GetMergeRequest(ctx, 1, 5).Return(mr, nil)
+		mc.EXPECT().ListCommitStatuses(ctx, 1, "abc123", "emoji-gate/terraform").Return([]*client.CommitStatus{&want}, nil)
+
+		assert.NoError(t, publishCommitStatus(ctx, mc, cfg, 1, decision))
+	})
+
+	errorCases := []struct {
+		name    string
+		setup   func(mc *clientmocks.MockClient)
+		wantErr string
+	}{
+		{
+			name: "Merge request lookup fails",
+			setup: func(mc *clientmocks.MockClient) {
+				mc.EXPECT().GetMergeRequest(ctx, 1, 5).Return(nil, errors.New("boom"))
+			},
+			wantErr: "failed to get merge request",
+		},
+		{
+			name: "Listing statuses fails",
+			setup: func(mc *clientmocks.MockClient) {
+				mc.EXPECT().GetMergeRequest(ctx, 1, 5).Return(mr, nil)
+				mc.EXPECT().ListCommitStatuses(ctx, 1, "abc123", gomock.Any()).Return(nil, errors.New("boom"))
+			},
+			wantErr: "failed to list commit statuses",
+		},
+		{
+			name: "Setting the status fails",
+			setup: func(mc *clientmocks.MockClient) {
+				mc.EXPECT().GetMergeRequest(ctx, 1, 5).Return(mr, nil)
+				mc.EXPECT().ListCommitStatuses(ctx, 1, "abc123", gomock.Any()).Return(nil, nil)
+				mc.EXPECT().SetCommitStatus(ctx, 1, "abc123", gomock.Any()).Return(errors.New("boom"))
+			},
+			wantErr: "failed to set commit status",
+		},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mc := clientmocks.NewMockClient(ctrl)
+			tc.setup(mc)
+
+			err := publishCommitStatus(ctx, mc, cfg, 1, decision)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package processor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// defaultSectionName is the section GitLab assigns to entries that appear
+// before any section header.
+const defaultSectionName = "codeowners"
+
+// sectionHeader matches GitLab section headers such as "[Section]",
+// "^[Optional Section]" and "[Section][2]", optionally followed by default owners.
+var sectionHeader = regexp.MustCompile(`^(\^)?\[([^\]]+)\](?:\[(\d+)\])?(?:\s+(.*))?$`)
+
+// section is a named group of CODEOWNERS entries. Within a section the last
+// matching entry wins, and every non-optional section that matches a path
+// must be approved independently. An approvals count of zero means the
+// section header did not specify one.
+type section struct {
+	name          string
+	optional      bool
+	approvals     int
+	defaultOwners []string
+	entries       []*entry
+}
+
+// entry is a single pattern line and the owners it assigns.
+type entry struct {
+	pattern *pattern
+	owners  []string
+	line    int
+}
+
+// match returns the last entry in the section whose pattern owns the path,
+// or nil.
+func (s *section) match(owns func(*pattern) bool) *entry {
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if owns(s.entries[i].pattern) {
+			return s.entries[i]
+		}
+	}
+	return nil
+}
+
+// Ruleset is a parsed CODEOWNERS file. It is built once per run and can be
+// queried for any number of paths without re-reading the file.
+// Owners are resolved separately, since a top-level group such as "@devops"
+// cannot be told apart from a user by its name: the members of group owners
+// and the owners known to be users are cached on the ruleset.
+type Ruleset struct {
+	sections     []*section
+	groupMembers map[string][]string
+	users        map[string]struct{}
+}
+
+// UnresolvedOwners returns the owners of the requirements that are not known
+// to be a group or a user yet, without duplicates.
+func (r *Ruleset) UnresolvedOwners(requirements []Requirement) []string {
+	var owners []string
+	seen := make(map[string]struct{})
+	for _, requirement := range requirements {
+		for _, owner := range requirement.Owners {
+			if _, ok := seen[owner]; ok {
+				continue
+			}
+			seen[owner] = struct{}{}
+			_, group := r.groupMembers[owner]
+			_, user := r.users[owner]
+			if !group && !user {
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return owners
+}
+
+// SetUserOwner records that an owner is a user rather than a group.
+func (r *Ruleset) SetUserOwner(owner string) {
+	if r.users == nil {
+		r.users = make(map[string]struct{})
+	}
+	r.users[owner] = struct{}{}
+}
+
+// SetGroupMembers records the usernames belonging to a group owner.
+func (r *Ruleset) SetGroupMembers(group string, usernames []string) {
+	if r.groupMembers == nil {
+		r.groupMembers = make(map[string][]string)
+	}
+	r.groupMembers[group] = usernames
+}
+
+// groupMembersOf returns the resolved members of every group among the owners.
+func (r *Ruleset) groupMembersOf(owners []string) map[string][]string {
+	var members map[string][]string
+	for _, owner := range owners {
+		if usernames, ok := r.groupMembers[owner]; ok {
+			if members == nil {
+				members = make(map[string][]string)
+			}
+			members[owner] = usernames
+		}
+	}
+	return members
+}
+
+// isOwner reports whether the user is one of the owners, either directly or
+// as a member of a group owner.
+func (r *Ruleset) isOwner(owners []string, username string) bool {
+	for _, owner := range owners {
+		if owner == username {
+			return true
+		}
+		if slices.Contains(r.groupMembers[owner], username) {
+			return true
+		}
+	}
+	return false
+}
+
+// Requirement describes the owners a single CODEOWNERS section assigns to a path.
+// Approvals is zero unless the section header sets an explicit "[N]" count.
+type Requirement struct {
+	Section   string   `json:"section"`
+	Optional  bool     `json:"optional"`
+	Approvals int      `json:"-"`
+	Pattern   string   `json:"pattern"`
+	Line      int      `json:"line"`
+	Owners    []string `json:"owners"`
+}
+
+// OwnersFor returns the requirement of every section with an entry owning
+// the directory, such as REPO_REL_DIR, in the order the sections appear in
+// the file.
+func (r *Ruleset) OwnersFor(dir string) []Requirement {
+	return r.owners(func(p *pattern) bool { return p.matchDir(dir) })
+}
+
+// OwnersForFile returns the requirement of every section with an entry owning
+// the file, in the order the sections appear in the file.
+func (r *Ruleset) OwnersForFile(filePath string) []Requirement {
+	return r.owners(func(p *pattern) bool { return p.matchFile(filePath) })
+}
+
+// OwnersForPath returns the requirements of an evaluated path, matching it as
+// a directory if it is the Terraform directory and as a file otherwise, such
+// as a file changed by the merge request.
+func (r *Ruleset) OwnersForPath(filePath, terraformPath string) []Requirement {
+	if slices.Equal(splitPath(filePath), splitPath(terraformPath)) {
+		return r.OwnersFor(filePath)
+	}
+	return r.OwnersForFile(filePath)
+}
+
+// owners returns the requirement of every section with an entry owning a
+// path, as reported by the owns function.
+func (r *Ruleset) owners(owns func(*pattern) bool) []Requirement {
+	var requirements []Requirement
+	for _, s := range r.sections {
+		matched := s.match(owns)
+		if matched == nil {
+			continue
+		}
+		requirements = append(requirements, Requirement{
+			Section:   s.name,
+			Optional:  s.optional,
+			Approvals: s.approvals,
+			Pattern:   matched.pattern.raw,
+			Line:      matched.line,
+			Owners:    matched.owners,
+		})
+	}
+	return requirements
+}
+
+// ParseCodeOwners reads CODEOWNERS content into a Ruleset. Sections sharing a
+// name (case-insensitively) are merged, as GitLab does.
+func ParseCodeOwners(codeowners io.Reader) (*Ruleset, error) {
+	current := &section{name: defaultSectionName}
+	sections := []*section{current}
+	byName := map[string]*section{defaultSectionName: current}
+
+	scanner := bufio.NewScanner(codeowners)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if header := sectionHeader.FindStringSubmatch(line); header != nil {
+			name := strings.TrimSpace(header[2])
+			key := strings.ToLower(name)
+			existing, ok := byName[key]
+			if !ok {
+				existing = &section{name: name}
+				byName[key] = existing
+				sections = append(sections, existing)
+			}
+			existing.optional = header[1] != ""
+			if header[3] != "" {
+				approvals, err := strconv.Atoi(header[3])
+				if err != nil || approvals < 1 {
+					return nil, fmt.Errorf("invalid approval count in section header on line %d: '%s'", lineNumber, line)
+				}
+				existing.approvals = approvals
+			}
+			if owners := parseOwners(splitFields(header[4])); len(owners) > 0 {
+				existing.defaultOwners = owners
+			}
+			current = existing
+			continue
+		}
+
+		parts := splitFields(line)
+		pathPattern, err := compilePattern(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CODEOWNERS entry on line %d: %w", lineNumber, err)
+		}
+
+		owners := parseOwners(parts[1:])
+		if len(owners) == 0 {
+			owners = current.defaultOwners
+		}
+		if len(owners) == 0 {
+			continue
+		}
+
+		current.entries = append(current.entries, &entry{pattern: pathPattern, owners: owners, line: lineNumber})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading CODEOWNERS content: %w", err)
+	}
+
+	return &Ruleset{sections: sections}, nil
+}
+
+// parseOwners strips the leading "@" from owner references.
+func parseOwners(fields []string) []string {
+	owners := make([]string, 0, len(fields))
+	for _, field := range fields {
+		owners = append(owners, strings.TrimPrefix(field, "@"))
+	}
+	return owners
+}
+
+// splitFields splits a CODEOWNERS line on unescaped whitespace, so patterns
+// containing escaped spaces (e.g. `docs\ folder`) stay intact.
+func splitFields(line string) []string {
+	var fields []string
+	var current strings.Builder
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			current.WriteRune(r)
+			escaped = true
+		case unicode.IsSpace(r):
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+
+	return fields
+}
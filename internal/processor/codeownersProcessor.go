@@ -1,10 +1,8 @@
 package processor
 
 import (
-	"bufio"
 	"fmt"
-	"io"
-	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/shini4i/atlantis-emoji-gate/internal/client"
@@ -13,9 +11,10 @@ import (
 
 //go:generate go tool mockgen -destination=mocks/mock_processor.go -package=mocks . Processor
 
-// Processor defines the contract for checking approvals against a CODEOWNERS file.
+// Processor defines the contract for checking approvals against parsed CODEOWNERS rules.
 type Processor interface {
-	CheckApproval(codeowners io.Reader, reaction *client.AwardEmoji, cfg config.GitlabConfig) (bool, error)
+	CheckApproval(rules *Ruleset, reactions []*client.AwardEmoji, cfg config.Config) *Decision
+	CheckApprovalForPaths(rules *Ruleset, paths []string, reactions []*client.AwardEmoji, cfg config.Config) *Decision
 }
 
 // approvalProcessor provides a concrete implementation of the Processor interface.
@@ -26,68 +25,222 @@ func NewProcessor() Processor {
 	return &approvalProcessor{}
 }
 
-// isPathMatch checks if a path matches a CODEOWNERS pattern.
-// Leading slashes are stripped from the pattern to normalize matching, since
-// Atlantis provides REPO_REL_DIR without a leading slash.
-func isPathMatch(pattern, path string) (bool, error) {
-	pattern = strings.TrimPrefix(pattern, "/")
-	if pattern == "*" {
-		return true, nil
-	}
-	return filepath.Match(pattern, path)
+// CheckApproval evaluates the given reactions against the CODEOWNERS rules for
+// the configured Terraform path. Every non-optional section matching the path
+// must be approved by as many distinct owners as the section requires, which
+// defaults to the globally configured number of approvals when the section
+// header has no "[N]" count. As on GitLab, optional sections never require an
+// approval, so a path only optional sections match is approved without one. A
+// blocking emoji from an owner of any matching section, optional or not,
+// vetoes the decision regardless of approvals.
+func (p *approvalProcessor) CheckApproval(rules *Ruleset, reactions []*client.AwardEmoji, cfg config.Config) *Decision {
+	return evaluate(rules, []string{cfg.TerraformPath}, false, reactions, cfg)
 }
 
-// CheckApproval determines if a given user reaction constitutes a valid approval.
-func (p *approvalProcessor) CheckApproval(codeowners io.Reader, reaction *client.AwardEmoji, cfg config.GitlabConfig) (bool, error) {
-	if reaction.Name != cfg.ApproveEmoji {
-		return false, nil
-	}
-	if !cfg.Insecure && reaction.User.Username == cfg.MrAuthor {
-		return false, nil
+// CheckApprovalForPaths evaluates the given reactions against the union of the
+// CODEOWNERS rules for every path, such as the files changed by the merge
+// request. A rule matching several paths is counted once. Paths no rule or
+// only optional sections match need no approval.
+func (p *approvalProcessor) CheckApprovalForPaths(rules *Ruleset, paths []string, reactions []*client.AwardEmoji, cfg config.Config) *Decision {
+	return evaluate(rules, paths, true, reactions, cfg)
+}
+
+// evaluate builds the decision for the paths, recording which paths each
+// section applies to when trackPaths is set.
+func evaluate(rules *Ruleset, paths []string, trackPaths bool, reactions []*client.AwardEmoji, cfg config.Config) *Decision {
+	approvers := approvingUsers(reactions, cfg)
+	decision := &Decision{Path: cfg.TerraformPath}
+	if trackPaths {
+		decision.Paths = paths
 	}
 
-	var applicableOwners []string
-	scanner := bufio.NewScanner(codeowners)
-	cleanedTerraformPath := filepath.Clean(cfg.TerraformPath)
+	// Sections are keyed by the CODEOWNERS line of their matching entry, which
+	// identifies both the section and the rule.
+	byLine := make(map[int]int)
+	var sectionPaths [][]string
+	for _, filePath := range paths {
+		for _, requirement := range rules.OwnersForPath(filePath, cfg.TerraformPath) {
+			index, seen := byLine[requirement.Line]
+			if !seen {
+				index = len(decision.Sections)
+				byLine[requirement.Line] = index
+				decision.Sections = append(decision.Sections, SectionResult{Requirement: requirement})
+				sectionPaths = append(sectionPaths, nil)
+			}
+			if !slices.Contains(sectionPaths[index], filePath) {
+				sectionPaths[index] = append(sectionPaths[index], filePath)
+			}
+		}
+	}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	// Approvals limited to directories only count for a section when they
+	// cover every path it applies to, so approvers are known once all paths are.
+	for index := range decision.Sections {
+		section := &decision.Sections[index]
+		section.Required = section.Approvals
+		if section.Required == 0 {
+			section.Required = max(cfg.RequiredApprovals, 1)
 		}
+		section.Members = rules.groupMembersOf(section.Owners)
+		section.Approvers = ownerApprovers(rules, section.Requirement, sectionPaths[index], approvers)
+		if trackPaths {
+			section.Paths = sectionPaths[index]
+		}
+		if !section.Optional {
+			decision.Required += section.Required
+			decision.Found += min(len(section.Approvers), section.Required)
+		}
+	}
+
+	requirements := make([]Requirement, 0, len(decision.Sections))
+	for _, section := range decision.Sections {
+		requirements = append(requirements, section.Requirement)
+	}
+	decision.BlockedBy = blockingOwners(rules, requirements, reactions, cfg)
 
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
+	decision.Approved = len(decision.Sections) > 0 && decision.Found >= decision.Required && len(decision.BlockedBy) == 0
+	decision.Reactions = explainReactions(reactions, decision, sectionPaths, cfg)
+	return decision
+}
+
+// explainReactions returns a verdict for every reaction, describing whether
+// and why it counted towards the decision. sectionPaths holds the paths each
+// section of the decision applies to.
+func explainReactions(reactions []*client.AwardEmoji, decision *Decision, sectionPaths [][]string, cfg config.Config) []ReactionVerdict {
+	verdicts := make([]ReactionVerdict, 0, len(reactions))
+	credited := make(map[string]map[string]struct{})
+	vetoed := make(map[string]struct{})
+
+	for _, reaction := range reactions {
+		username := reaction.User.Username
+		verdict := ReactionVerdict{User: username, Emoji: reaction.Name, Source: reaction.Source, Verdict: VerdictRejected}
+
+		if IsBlockingEmoji(reaction.Name, cfg) {
+			_, alreadyVetoed := vetoed[username]
+			switch {
+			case !slices.Contains(decision.BlockedBy, username):
+				verdict.Reason = "not an owner of any matching section"
+			case alreadyVetoed:
+				verdict.Reason = "duplicate veto, the user is counted once"
+			default:
+				vetoed[username] = struct{}{}
+				verdict.Verdict = VerdictBlocked
+				verdict.Reason = "code owner vetoed the apply"
+			}
+			verdicts = append(verdicts, verdict)
 			continue
 		}
 
-		pathPattern := parts[0]
-		matched, err := isPathMatch(pathPattern, cleanedTerraformPath)
-		if err != nil {
-			return false, fmt.Errorf("invalid pattern '%s' in CODEOWNERS: %w", pathPattern, err)
-		}
+		scope, ok := approvalScope(reaction, cfg)
+		switch {
+		case !ok:
+			verdict.Reason = fmt.Sprintf("emoji %q is not an approval emoji", reaction.Name)
+		case !cfg.Insecure && username == cfg.MrAuthor:
+			verdict.Reason = "merge request author cannot approve their own merge request"
+		default:
+			reactionGrant := grant{scope: scope, paths: reaction.Paths}
+			var sections, newSections []string
+			for index, section := range decision.Sections {
+				if !slices.Contains(section.Approvers, username) {
+					continue
+				}
+				if !slices.ContainsFunc(sectionPaths[index], func(filePath string) bool {
+					return reactionGrant.covers(section.Section, filePath)
+				}) {
+					continue
+				}
+				sections = append(sections, section.Section)
+				if _, done := credited[username][section.Section]; !done {
+					newSections = append(newSections, section.Section)
+				}
+			}
 
-		if matched {
-			applicableOwners = make([]string, 0, len(parts)-1)
-			for _, owner := range parts[1:] {
-				applicableOwners = append(applicableOwners, strings.TrimPrefix(owner, "@"))
+			switch {
+			case len(sections) == 0 && scope != "":
+				verdict.Reason = fmt.Sprintf("emoji is limited to section %q, which the user does not own for this path", scope)
+			case len(sections) == 0 && len(reaction.Paths) > 0:
+				verdict.Reason = fmt.Sprintf("comment approves %s, which does not cover every path of a section the user owns", strings.Join(reaction.Paths, ", "))
+			case len(sections) == 0:
+				verdict.Reason = "not an owner of any matching section"
+			case len(newSections) == 0:
+				verdict.Reason = "duplicate approval, the user is counted once"
+			default:
+				if credited[username] == nil {
+					credited[username] = make(map[string]struct{})
+				}
+				for _, section := range newSections {
+					credited[username][section] = struct{}{}
+				}
+				verdict.Verdict = VerdictApproved
+				verdict.Reason = "counts towards " + strings.Join(newSections, ", ")
 			}
 		}
+		verdicts = append(verdicts, verdict)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return false, fmt.Errorf("error reading CODEOWNERS content: %w", err)
-	}
+	return verdicts
+}
 
-	if applicableOwners == nil {
-		return false, nil
+// blockingOwners returns the distinct owners of any matching requirement who
+// reacted with one of the configured blocking emojis.
+func blockingOwners(rules *Ruleset, requirements []Requirement, reactions []*client.AwardEmoji, cfg config.Config) []string {
+	var blockers []string
+	seen := make(map[string]struct{})
+	for _, reaction := range reactions {
+		if !IsBlockingEmoji(reaction.Name, cfg) {
+			continue
+		}
+		username := reaction.User.Username
+		if _, ok := seen[username]; ok {
+			continue
+		}
+		for _, requirement := range requirements {
+			if rules.isOwner(requirement.Owners, username) {
+				seen[username] = struct{}{}
+				blockers = append(blockers, username)
+				break
+			}
+		}
 	}
+	return blockers
+}
 
-	for _, owner := range applicableOwners {
-		if owner == reaction.User.Username {
-			return true, nil
+// approvingUsers returns the distinct users whose reactions count as approvals,
+// in reaction order, ignoring other emojis and, unless insecure mode is on,
+// the MR author. Repeated reactions from the same user are merged, widening
+// the sections and paths their approval applies to.
+func approvingUsers(reactions []*client.AwardEmoji, cfg config.Config) []*approver {
+	var approvers []*approver
+	byUser := make(map[string]*approver)
+	for _, reaction := range reactions {
+		scope, ok := approvalScope(reaction, cfg)
+		if !ok {
+			continue
 		}
+		if !cfg.Insecure && reaction.User.Username == cfg.MrAuthor {
+			continue
+		}
+
+		a, seen := byUser[reaction.User.Username]
+		if !seen {
+			a = &approver{username: reaction.User.Username}
+			byUser[a.username] = a
+			approvers = append(approvers, a)
+		}
+		a.grants = append(a.grants, grant{scope: scope, paths: reaction.Paths})
 	}
+	return approvers
+}
 
-	return false, nil
+// ownerApprovers returns the approvers whose approval applies to the section
+// for all of its paths and who are owners, either directly or through
+// membership of a group owner.
+func ownerApprovers(rules *Ruleset, requirement Requirement, paths []string, approvers []*approver) []string {
+	var matched []string
+	for _, a := range approvers {
+		if a.covers(requirement.Section, paths) && rules.isOwner(requirement.Owners, a.username) {
+			matched = append(matched, a.username)
+		}
+	}
+	return matched
 }
@@ -21,8 +21,8 @@ func (f FaultyReader) Read(p []byte) (n int, err error) {
 func TestCheckApproval(t *testing.T) {
 	// Base configuration and reaction user used across many tests.
 	// We will override fields as needed in specific test cases.
-	baseCfg := config.GitlabConfig{
-		ApproveEmoji:  "thumbsup",
+	baseCfg := config.Config{
+		ApproveEmojis: []string{"thumbsup"},
 		MrAuthor:      "mr_author",
 		Insecure:      false,
 		TerraformPath: "project/staging/app",
@@ -40,7 +40,7 @@ func TestCheckApproval(t *testing.T) {
 		name         string
 		codeowners   io.Reader
 		reaction     *client.AwardEmoji
-		config       config.GitlabConfig
+		config       config.Config
 		wantApproved bool
 		wantErr      string // Substring of the expected error, if any.
 	}{
@@ -53,9 +53,9 @@ func TestCheckApproval(t *testing.T) {
 		},
 		{
 			name: "Success: Direct path glob matches",
-			// Using 'project/staging/*' is a more precise glob pattern than 'project/staging/'.
+			// Using 'project/staging/*/' is a more precise glob pattern than 'project/staging/'.
 			// This will correctly match 'project/staging/app'.
-			codeowners:   strings.NewReader("project/staging/* @approver"),
+			codeowners:   strings.NewReader("project/staging/*/ @approver"),
 			reaction:     approvingUser,
 			config:       baseCfg,
 			wantApproved: true,
@@ -94,7 +94,7 @@ func TestCheckApproval(t *testing.T) {
 				Name: "thumbsup",
 				User: client.User{Username: "mr_author"},
 			},
-			config: func() config.GitlabConfig {
+			config: func() config.Config {
 				c := baseCfg
 				c.Insecure = true // Override insecure mode
 				return c
@@ -142,7 +142,7 @@ func TestCheckApproval(t *testing.T) {
 			name: "CRITICAL: Last matching rule wins (user in last rule)",
 			codeowners: strings.NewReader(`
 				* @generic_admin
-				project/staging/* @approver
+				project/staging/*/ @approver
 			`),
 			reaction:     approvingUser,
 			config:       baseCfg,
@@ -152,7 +152,7 @@ func TestCheckApproval(t *testing.T) {
 			name: "CRITICAL: Last matching rule wins (user not in last rule)",
 			codeowners: strings.NewReader(`
 				* @approver
-				project/staging/* @stage_lead_only
+				project/staging/*/ @stage_lead_only
 			`),
 			reaction:     approvingUser,
 			config:       baseCfg,
@@ -162,7 +162,7 @@ func TestCheckApproval(t *testing.T) {
 			name: "CRITICAL: Multiple owners on the last matching line",
 			codeowners: strings.NewReader(`
 				* @admin
-				project/staging/* @lead @other_approver @approver
+				project/staging/*/ @lead @other_approver @approver
 			`),
 			reaction:     approvingUser,
 			config:       baseCfg,
@@ -170,16 +170,44 @@ func TestCheckApproval(t *testing.T) {
 		},
 		{
 			name:         "CRITICAL: Leading slash in pattern is stripped for matching",
+			codeowners:   strings.NewReader("/project/staging/*/ @approver"),
+			reaction:     approvingUser,
+			config:       baseCfg,
+			wantApproved: true,
+		},
+		{
+			name:         "Wildcard pattern only owns the files directly inside a directory",
 			codeowners:   strings.NewReader("/project/staging/* @approver"),
 			reaction:     approvingUser,
 			config:       baseCfg,
+			wantApproved: false,
+		},
+		{
+			name:         "Directory pattern owns nested paths",
+			codeowners:   strings.NewReader("/project @approver"),
+			reaction:     approvingUser,
+			config:       baseCfg,
+			wantApproved: true,
+		},
+		{
+			name:         "Globstar pattern matches at any depth",
+			codeowners:   strings.NewReader("/**/app @approver"),
+			reaction:     approvingUser,
+			config:       baseCfg,
 			wantApproved: true,
 		},
+		{
+			name:         "Escaped whitespace keeps the pattern in one field",
+			codeowners:   strings.NewReader(`/project/staging\ app @approver`),
+			reaction:     approvingUser,
+			config:       baseCfg,
+			wantApproved: false,
+		},
 		{
 			name: "CRITICAL: Leading slash pattern with last-match-wins",
 			codeowners: strings.NewReader(`
 				* @generic_admin
-				/project/staging/* @approver
+				/project/staging/*/ @approver
 			`),
 			reaction:     approvingUser,
 			config:       baseCfg,
@@ -190,17 +218,589 @@ func TestCheckApproval(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Act
-			isApproved, err := proc.CheckApproval(tc.codeowners, tc.reaction, tc.config)
+			rules, err := ParseCodeOwners(tc.codeowners)
 
 			// Assert
 			if tc.wantErr != "" {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tc.wantErr)
-			} else {
-				assert.NoError(t, err)
+				return
 			}
+			assert.NoError(t, err)
+
+			decision := proc.CheckApproval(rules, []*client.AwardEmoji{tc.reaction}, tc.config)
+			assert.Equal(t, tc.wantApproved, decision.Approved)
+		})
+	}
+}
+
+func TestCheckApproval_Sections(t *testing.T) {
+	cfg := config.Config{
+		ApproveEmojis: []string{"thumbsup"},
+		MrAuthor:      "mr_author",
+		TerraformPath: "terraform/deploy",
+	}
+
+	approval := func(username string) *client.AwardEmoji {
+		return &client.AwardEmoji{Name: "thumbsup", User: client.User{Username: username}}
+	}
+
+	proc := NewProcessor()
+
+	testCases := []struct {
+		name         string
+		codeowners   string
+		reactions    []*client.AwardEmoji
+		wantApproved bool
+	}{
+		{
+			name: "Every matching required section must approve",
+			codeowners: `
+				/terraform @infra
+				[Security]
+				/terraform/deploy @sec
+			`,
+			reactions:    []*client.AwardEmoji{approval("infra")},
+			wantApproved: false,
+		},
+		{
+			name: "Approvals from all required sections succeed",
+			codeowners: `
+				/terraform @infra
+				[Security]
+				/terraform/deploy @sec
+			`,
+			reactions:    []*client.AwardEmoji{approval("infra"), approval("sec")},
+			wantApproved: true,
+		},
+		{
+			name: "Sections that do not match the path are not required",
+			codeowners: `
+				/terraform @infra
+				[Docs]
+				/docs @writer
+			`,
+			reactions:    []*client.AwardEmoji{approval("infra")},
+			wantApproved: true,
+		},
+		{
+			name: "Optional sections are not required",
+			codeowners: `
+				/terraform @infra
+				^[Security]
+				/terraform/deploy @sec
+			`,
+			reactions:    []*client.AwardEmoji{approval("infra")},
+			wantApproved: true,
+		},
+		{
+			name: "Only optional sections matching need no approval",
+			codeowners: `
+				^[Security]
+				/terraform/deploy @sec
+			`,
+			reactions:    []*client.AwardEmoji{approval("someone")},
+			wantApproved: true,
+		},
+		{
+			name: "Only optional sections matching are satisfied by an owner",
+			codeowners: `
+				^[Security]
+				/terraform/deploy @sec
+			`,
+			reactions:    []*client.AwardEmoji{approval("sec")},
+			wantApproved: true,
+		},
+		{
+			name: "Section approval count requires distinct owners",
+			codeowners: `
+				[Platform][2]
+				/terraform @alice @bob @carol
+			`,
+			reactions:    []*client.AwardEmoji{approval("alice"), approval("alice")},
+			wantApproved: false,
+		},
+		{
+			name: "Section approval count is met",
+			codeowners: `
+				[Platform][2]
+				/terraform @alice @bob @carol
+			`,
+			reactions:    []*client.AwardEmoji{approval("alice"), approval("carol")},
+			wantApproved: true,
+		},
+		{
+			name: "Entries without owners use the section default owners",
+			codeowners: `
+				[Platform] @platform-lead
+				/terraform
+			`,
+			reactions:    []*client.AwardEmoji{approval("platform-lead")},
+			wantApproved: true,
+		},
+		{
+			name: "Last match wins within a section",
+			codeowners: `
+				[Platform]
+				/terraform @infra
+				/terraform/deploy @deployer
+			`,
+			reactions:    []*client.AwardEmoji{approval("infra")},
+			wantApproved: false,
+		},
+		{
+			name: "Sections with the same name are merged",
+			codeowners: `
+				[Platform]
+				/terraform @infra
+				[Docs]
+				/docs @writer
+				[platform]
+				/terraform/deploy @deployer
+			`,
+			reactions:    []*client.AwardEmoji{approval("deployer")},
+			wantApproved: true,
+		},
+		{
+			name: "Self-approval does not count towards a section",
+			codeowners: `
+				[Platform][2]
+				/terraform @alice @mr_author
+			`,
+			reactions:    []*client.AwardEmoji{approval("alice"), approval("mr_author")},
+			wantApproved: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := ParseCodeOwners(strings.NewReader(tc.codeowners))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantApproved, proc.CheckApproval(rules, tc.reactions, cfg).Approved)
+		})
+	}
+}
+
+func TestCheckApproval_GroupOwners(t *testing.T) {
+	cfg := config.Config{
+		ApproveEmojis: []string{"thumbsup"},
+		MrAuthor:      "mr_author",
+		TerraformPath: "terraform/deploy",
+	}
+
+	approval := func(username string) *client.AwardEmoji {
+		return &client.AwardEmoji{Name: "thumbsup", User: client.User{Username: username}}
+	}
+
+	newRules := func(t *testing.T, content string) *Ruleset {
+		t.Helper()
+		rules, err := ParseCodeOwners(strings.NewReader(content))
+		assert.NoError(t, err)
+		rules.SetGroupMembers("platform/sre", []string{"alice", "bob", "mr_author"})
+		return rules
+	}
+
+	proc := NewProcessor()
+
+	t.Run("Group member can approve", func(t *testing.T) {
+		rules := newRules(t, "/terraform @platform/sre")
+		assert.True(t, proc.CheckApproval(rules, []*client.AwardEmoji{approval("alice")}, cfg).Approved)
+	})
+
+	t.Run("Non-member cannot approve", func(t *testing.T) {
+		rules := newRules(t, "/terraform @platform/sre")
+		assert.False(t, proc.CheckApproval(rules, []*client.AwardEmoji{approval("mallory")}, cfg).Approved)
+	})
+
+	t.Run("Unresolved group does not match its name as a user", func(t *testing.T) {
+		rules, err := ParseCodeOwners(strings.NewReader("/terraform @platform/sre"))
+		assert.NoError(t, err)
+		assert.False(t, proc.CheckApproval(rules, []*client.AwardEmoji{approval("alice")}, cfg).Approved)
+	})
+
+	t.Run("Group members count as distinct approvals", func(t *testing.T) {
+		rules := newRules(t, "[Platform][2]\n/terraform @platform/sre @alice")
+		assert.False(t, proc.CheckApproval(rules, []*client.AwardEmoji{approval("alice")}, cfg).Approved)
+		assert.True(t, proc.CheckApproval(rules, []*client.AwardEmoji{approval("alice"), approval("bob")}, cfg).Approved)
+	})
+
+	t.Run("MR author in group cannot self-approve", func(t *testing.T) {
+		rules := newRules(t, "/terraform @platform/sre")
+		assert.False(t, proc.CheckApproval(rules, []*client.AwardEmoji{approval("mr_author")}, cfg).Approved)
+	})
+}
+
+func TestCheckApproval_RequiredApprovals(t *testing.T) {
+	approval := func(username string) *client.AwardEmoji {
+		return &client.AwardEmoji{Name: "thumbsup", User: client.User{Username: username}}
+	}
+
+	proc := NewProcessor()
+
+	t.Run("Global count applies to sections without an explicit count", func(t *testing.T) {
+		cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, TerraformPath: "terraform", RequiredApprovals: 2}
+		rules, err := ParseCodeOwners(strings.NewReader("/terraform @alice @bob @carol"))
+		assert.NoError(t, err)
+
+		decision := proc.CheckApproval(rules, []*client.AwardEmoji{approval("alice"), approval("alice")}, cfg)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, 1, decision.Found)
+		assert.Equal(t, 2, decision.Required)
+		assert.Len(t, decision.Missing(), 1)
+
+		decision = proc.CheckApproval(rules, []*client.AwardEmoji{approval("alice"), approval("carol")}, cfg)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, 2, decision.Found)
+		assert.Empty(t, decision.Missing())
+	})
+
+	t.Run("Section count overrides the global count", func(t *testing.T) {
+		cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, TerraformPath: "terraform", RequiredApprovals: 2}
+		rules, err := ParseCodeOwners(strings.NewReader(`
+			[Platform][1]
+			/terraform @alice @bob
+			[Security][3]
+			/terraform @sec1 @sec2 @sec3
+		`))
+		assert.NoError(t, err)
+
+		decision := proc.CheckApproval(rules, []*client.AwardEmoji{
+			approval("alice"), approval("sec1"), approval("sec2"),
+		}, cfg)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, 3, decision.Found)
+		assert.Equal(t, 4, decision.Required)
 
-			assert.Equal(t, tc.wantApproved, isApproved)
+		missing := decision.Missing()
+		assert.Len(t, missing, 1)
+		assert.Equal(t, "Security", missing[0].Section)
+		assert.Equal(t, []string{"sec1", "sec2"}, missing[0].Approvers)
+		assert.Equal(t, 3, missing[0].Required)
+	})
+
+	t.Run("Extra approvals do not inflate the found count", func(t *testing.T) {
+		cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, TerraformPath: "terraform", RequiredApprovals: 1}
+		rules, err := ParseCodeOwners(strings.NewReader("/terraform @alice @bob"))
+		assert.NoError(t, err)
+
+		decision := proc.CheckApproval(rules, []*client.AwardEmoji{approval("alice"), approval("bob")}, cfg)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, 1, decision.Found)
+		assert.Equal(t, 1, decision.Required)
+		assert.Equal(t, []string{"alice", "bob"}, decision.Sections[0].Approvers)
+	})
+
+	t.Run("Unowned path requires nothing but is not approved", func(t *testing.T) {
+		cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, TerraformPath: "terraform", RequiredApprovals: 1}
+		rules, err := ParseCodeOwners(strings.NewReader("/docs @writer"))
+		assert.NoError(t, err)
+
+		decision := proc.CheckApproval(rules, []*client.AwardEmoji{approval("writer")}, cfg)
+		assert.False(t, decision.Approved)
+		assert.Empty(t, decision.Sections)
+	})
+}
+
+func TestCheckApprovalForPaths(t *testing.T) {
+	approval := func(username string) *client.AwardEmoji {
+		return &client.AwardEmoji{Name: "thumbsup", User: client.User{Username: username}}
+	}
+
+	cfg := config.Config{ApproveEmojis: []string{"thumbsup"}, BlockEmoji: []string{"thumbsdown"}, TerraformPath: "terraform", RequiredApprovals: 1}
+	paths := []string{"terraform", "terraform/main.tf", "modules/vpc/main.tf", "README.md"}
+	rules, err := ParseCodeOwners(strings.NewReader(`
+		/terraform @alice
+		/modules/vpc @bob
+
+		^[Docs]
+		/docs @writer
+	`))
+	assert.NoError(t, err)
+
+	proc := NewProcessor()
+
+	t.Run("Every owned path must be approved", func(t *testing.T) {
+		decision := proc.CheckApprovalForPaths(rules, paths, []*client.AwardEmoji{approval("alice")}, cfg)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, 1, decision.Found)
+		assert.Equal(t, 2, decision.Required)
+		assert.Equal(t, paths, decision.Paths)
+
+		missing := decision.Missing()
+		if assert.Len(t, missing, 1) {
+			assert.Equal(t, "/modules/vpc", missing[0].Pattern)
+			assert.Equal(t, []string{"modules/vpc/main.tf"}, missing[0].Paths)
+		}
+	})
+
+	t.Run("A rule matching several paths is counted once", func(t *testing.T) {
+		decision := proc.CheckApprovalForPaths(rules, paths, []*client.AwardEmoji{approval("alice"), approval("bob")}, cfg)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, 2, decision.Found)
+		assert.Equal(t, 2, decision.Required)
+		if assert.Len(t, decision.Sections, 2) {
+			assert.Equal(t, []string{"terraform", "terraform/main.tf"}, decision.Sections[0].Paths)
+		}
+	})
+
+	t.Run("Paths matched only by optional sections need no approval", func(t *testing.T) {
+		docs := []string{"terraform", "docs/index.md", "docs/usage.md"}
+
+		decision := proc.CheckApprovalForPaths(rules, docs, []*client.AwardEmoji{approval("alice")}, cfg)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, 1, decision.Found)
+		assert.Equal(t, 1, decision.Required)
+		if assert.Len(t, decision.Sections, 2) {
+			assert.True(t, decision.Sections[1].Optional)
+			assert.Equal(t, []string{"docs/index.md", "docs/usage.md"}, decision.Sections[1].Paths)
+		}
+	})
+
+	t.Run("Owners of any evaluated path can veto", func(t *testing.T) {
+		decision := proc.CheckApprovalForPaths(rules, paths, []*client.AwardEmoji{
+			approval("alice"),
+			approval("bob"),
+			{Name: "thumbsdown", User: client.User{Username: "bob"}},
+		}, cfg)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, []string{"bob"}, decision.BlockedBy)
+	})
+
+	t.Run("Unowned paths need no approval", func(t *testing.T) {
+		decision := proc.CheckApprovalForPaths(rules, []string{"terraform", "README.md"}, []*client.AwardEmoji{approval("alice")}, cfg)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, 1, decision.Required)
+	})
+
+	t.Run("Approval comments only count for the sections of their paths", func(t *testing.T) {
+		comment := func(username string, paths ...string) *client.AwardEmoji {
+			return &client.AwardEmoji{User: client.User{Username: username}, Source: config.ApprovalSourceComment, Paths: paths}
+		}
+
+		decision := proc.CheckApprovalForPaths(rules, paths, []*client.AwardEmoji{approval("alice"), comment("bob", "terraform")}, cfg)
+		assert.False(t, decision.Approved)
+		assert.Equal(t, 1, decision.Found)
+		assert.Equal(t, ReactionVerdict{
+			User:    "bob",
+			Source:  config.ApprovalSourceComment,
+			Verdict: VerdictRejected,
+			Reason:  "comment approves terraform, which does not cover every path of a section the user owns",
+		}, decision.Reactions[1])
+
+		decision = proc.CheckApprovalForPaths(rules, paths, []*client.AwardEmoji{approval("alice"), comment("bob", "modules/vpc")}, cfg)
+		assert.True(t, decision.Approved)
+		assert.Equal(t, VerdictApproved, decision.Reactions[1].Verdict)
+	})
+
+	t.Run("Approval comments must cover every path of a section", func(t *testing.T) {
+		comment := &client.AwardEmoji{User: client.User{Username: "alice"}, Source: config.ApprovalSourceComment, Paths: []string{"terraform/deploy"}}
+
+		decision := proc.CheckApprovalForPaths(rules, []string{"terraform/deploy", "terraform/provision/main.tf"}, []*client.AwardEmoji{comment}, cfg)
+		assert.False(t, decision.Approved)
+		assert.Empty(t, decision.Sections[0].Approvers)
+	})
+}
+
+func TestCheckApproval_BlockEmoji(t *testing.T) {
+	cfg := config.Config{
+		ApproveEmojis: []string{"thumbsup"},
+		BlockEmoji:    []string{"thumbsdown", "no_entry"},
+		MrAuthor:      "mr_author",
+		TerraformPath: "terraform/deploy",
+	}
+
+	reaction := func(name, username string) *client.AwardEmoji {
+		return &client.AwardEmoji{Name: name, User: client.User{Username: username}}
+	}
+
+	rules, err := ParseCodeOwners(strings.NewReader(`
+		/terraform @alice @bob @platform/sre
+		^[Security]
+		/terraform/deploy @sec
+	`))
+	assert.NoError(t, err)
+	rules.SetGroupMembers("platform/sre", []string{"carol"})
+
+	proc := NewProcessor()
+
+	testCases := []struct {
+		name          string
+		reactions     []*client.AwardEmoji
+		wantApproved  bool
+		wantBlockedBy []string
+	}{
+		{
+			name:         "Approval without blocks passes",
+			reactions:    []*client.AwardEmoji{reaction("thumbsup", "alice")},
+			wantApproved: true,
+		},
+		{
+			name:          "Owner veto overrides approvals",
+			reactions:     []*client.AwardEmoji{reaction("thumbsup", "alice"), reaction("thumbsdown", "bob")},
+			wantBlockedBy: []string{"bob"},
+		},
+		{
+			name:          "Any configured blocking emoji vetoes",
+			reactions:     []*client.AwardEmoji{reaction("thumbsup", "alice"), reaction("no_entry", "bob")},
+			wantBlockedBy: []string{"bob"},
+		},
+		{
+			name:          "Group member veto is honored",
+			reactions:     []*client.AwardEmoji{reaction("thumbsup", "alice"), reaction("thumbsdown", "carol")},
+			wantBlockedBy: []string{"carol"},
+		},
+		{
+			name:          "Optional section owners can veto",
+			reactions:     []*client.AwardEmoji{reaction("thumbsup", "alice"), reaction("thumbsdown", "sec")},
+			wantBlockedBy: []string{"sec"},
+		},
+		{
+			name:         "Non-owner blocks are ignored",
+			reactions:    []*client.AwardEmoji{reaction("thumbsup", "alice"), reaction("thumbsdown", "mallory")},
+			wantApproved: true,
+		},
+		{
+			name:          "Repeated blocks are reported once",
+			reactions:     []*client.AwardEmoji{reaction("thumbsdown", "bob"), reaction("no_entry", "bob")},
+			wantBlockedBy: []string{"bob"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision := proc.CheckApproval(rules, tc.reactions, cfg)
+			assert.Equal(t, tc.wantApproved, decision.Approved)
+			assert.Equal(t, tc.wantBlockedBy, decision.BlockedBy)
 		})
 	}
+
+	t.Run("Blocking is disabled without configured emojis", func(t *testing.T) {
+		noBlockCfg := cfg
+		noBlockCfg.BlockEmoji = nil
+		decision := proc.CheckApproval(rules, []*client.AwardEmoji{reaction("thumbsup", "alice"), reaction("thumbsdown", "bob")}, noBlockCfg)
+		assert.True(t, decision.Approved)
+		assert.Empty(t, decision.BlockedBy)
+	})
+}
+
+func TestCheckApproval_ApprovalEmojis(t *testing.T) {
+	cfg := config.Config{
+		ApproveEmojis:      []string{"thumbsup", "white_check_mark"},
+		ApproveEmojiScopes: map[string]string{"lock": "Security"},
+		NormalizeSkinTones: true,
+		MrAuthor:           "mr_author",
+		TerraformPath:      "terraform/deploy",
+	}
+
+	reaction := func(name, username string) *client.AwardEmoji {
+		return &client.AwardEmoji{Name: name, User: client.User{Username: username}}
+	}
+
+	rules, err := ParseCodeOwners(strings.NewReader(`
+		/terraform @alice @bob
+		[Security]
+		/terraform @alice @sec
+	`))
+	assert.NoError(t, err)
+
+	proc := NewProcessor()
+
+	testCases := []struct {
+		name         string
+		reactions    []*client.AwardEmoji
+		wantApproved bool
+	}{
+		{
+			name:         "Any configured emoji approves every section",
+			reactions:    []*client.AwardEmoji{reaction("white_check_mark", "alice")},
+			wantApproved: true,
+		},
+		{
+			name:         "Skin-tone variants are normalized",
+			reactions:    []*client.AwardEmoji{reaction("thumbsup_tone4", "alice")},
+			wantApproved: true,
+		},
+		{
+			name:         "Scoped emoji only approves its section",
+			reactions:    []*client.AwardEmoji{reaction("lock", "alice")},
+			wantApproved: false,
+		},
+		{
+			name:         "Scoped and unscoped emojis combine",
+			reactions:    []*client.AwardEmoji{reaction("thumbsup", "bob"), reaction("lock", "sec")},
+			wantApproved: true,
+		},
+		{
+			name:         "Unknown emoji is ignored",
+			reactions:    []*client.AwardEmoji{reaction("rocket", "alice")},
+			wantApproved: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantApproved, proc.CheckApproval(rules, tc.reactions, cfg).Approved)
+		})
+	}
+
+	t.Run("Scoped approvals are reported per section", func(t *testing.T) {
+		decision := proc.CheckApproval(rules, []*client.AwardEmoji{reaction("lock", "alice")}, cfg)
+		assert.Empty(t, decision.Sections[0].Approvers)
+		assert.Equal(t, []string{"alice"}, decision.Sections[1].Approvers)
+	})
+}
+
+func TestCheckApproval_ReactionVerdicts(t *testing.T) {
+	cfg := config.Config{
+		ApproveEmojis:      []string{"thumbsup"},
+		ApproveEmojiScopes: map[string]string{"lock": "Security"},
+		BlockEmoji:         []string{"thumbsdown"},
+		MrAuthor:           "mr_author",
+		TerraformPath:      "terraform/deploy",
+	}
+
+	rules, err := ParseCodeOwners(strings.NewReader(`
+		/terraform @alice @bob @mr_author
+		[Security]
+		/terraform/deploy @sec
+	`))
+	assert.NoError(t, err)
+
+	reaction := func(name, username string) *client.AwardEmoji {
+		return &client.AwardEmoji{Name: name, User: client.User{Username: username}}
+	}
+
+	decision := NewProcessor().CheckApproval(rules, []*client.AwardEmoji{
+		reaction("thumbsup", "alice"),
+		reaction("thumbsup", "alice"),
+		reaction("rocket", "bob"),
+		reaction("thumbsup", "mr_author"),
+		reaction("thumbsup", "stranger"),
+		reaction("lock", "bob"),
+		reaction("thumbsdown", "sec"),
+		reaction("thumbsdown", "stranger"),
+	}, cfg)
+
+	want := []struct {
+		verdict string
+		reason  string
+	}{
+		{VerdictApproved, "counts towards codeowners"},
+		{VerdictRejected, "duplicate approval"},
+		{VerdictRejected, `emoji "rocket" is not an approval emoji`},
+		{VerdictRejected, "cannot approve their own merge request"},
+		{VerdictRejected, "not an owner of any matching section"},
+		{VerdictRejected, `limited to section "Security"`},
+		{VerdictBlocked, "vetoed"},
+		{VerdictRejected, "not an owner of any matching section"},
+	}
+
+	assert.Len(t, decision.Reactions, len(want))
+	for i, w := range want {
+		assert.Equal(t, w.verdict, decision.Reactions[i].Verdict, "reaction %d", i)
+		assert.Contains(t, decision.Reactions[i].Reason, w.reason, "reaction %d", i)
+	}
+	assert.Equal(t, "terraform/deploy", decision.Path)
+	assert.Equal(t, 2, decision.Sections[0].Line)
+	assert.Equal(t, 4, decision.Sections[1].Line)
 }
@@ -0,0 +1,177 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCodeOwners(t *testing.T) {
+	t.Run("Entries before any header belong to the default section", func(t *testing.T) {
+		rules, err := ParseCodeOwners(strings.NewReader("* @admin\n"))
+		require.NoError(t, err)
+		require.Len(t, rules.sections, 1)
+		assert.Equal(t, defaultSectionName, rules.sections[0].name)
+		assert.False(t, rules.sections[0].optional)
+		assert.Equal(t, 0, rules.sections[0].approvals)
+		assert.Equal(t, []string{"admin"}, rules.sections[0].entries[0].owners)
+	})
+
+	t.Run("Section headers", func(t *testing.T) {
+		content := `
+[Platform Team][2] @lead @deputy
+/terraform
+^[Docs]
+/docs @writer
+`
+		rules, err := ParseCodeOwners(strings.NewReader(content))
+		require.NoError(t, err)
+		require.Len(t, rules.sections, 3)
+
+		platform := rules.sections[1]
+		assert.Equal(t, "Platform Team", platform.name)
+		assert.False(t, platform.optional)
+		assert.Equal(t, 2, platform.approvals)
+		assert.Equal(t, []string{"lead", "deputy"}, platform.defaultOwners)
+		assert.Equal(t, []string{"lead", "deputy"}, platform.entries[0].owners)
+
+		docs := rules.sections[2]
+		assert.Equal(t, "Docs", docs.name)
+		assert.True(t, docs.optional)
+		assert.Equal(t, 0, docs.approvals)
+	})
+
+	t.Run("Entries without any owners are ignored", func(t *testing.T) {
+		rules, err := ParseCodeOwners(strings.NewReader("/terraform\n"))
+		require.NoError(t, err)
+		assert.Empty(t, rules.sections[0].entries)
+	})
+
+	t.Run("Character classes at line start are patterns, not headers", func(t *testing.T) {
+		rules, err := ParseCodeOwners(strings.NewReader("[abc]*.tf @owner\n"))
+		require.NoError(t, err)
+		require.Len(t, rules.sections, 1)
+		require.Len(t, rules.sections[0].entries, 1)
+		assert.True(t, rules.sections[0].entries[0].pattern.matchFile("b.tf"))
+	})
+
+	t.Run("Escaped hash is a pattern, not a comment", func(t *testing.T) {
+		rules, err := ParseCodeOwners(strings.NewReader("# comment\n\\#infra @owner\n"))
+		require.NoError(t, err)
+		require.Len(t, rules.sections[0].entries, 1)
+		assert.True(t, rules.sections[0].entries[0].pattern.matchFile("#infra"))
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		_, err := ParseCodeOwners(strings.NewReader("* @admin\n[* @owner\n"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "line 2")
+		assert.Contains(t, err.Error(), "invalid pattern")
+	})
+}
+
+func TestRuleset_OwnersFor(t *testing.T) {
+	content := `
+* @admin
+/terraform @infra
+/terraform/deploy @deployer
+[Security][2]
+/terraform/deploy @alice @bob
+^[Docs]
+/docs @writer
+`
+	rules, err := ParseCodeOwners(strings.NewReader(content))
+	require.NoError(t, err)
+
+	t.Run("Returns the last match of every matching section", func(t *testing.T) {
+		assert.Equal(t, []Requirement{
+			{Section: defaultSectionName, Pattern: "/terraform/deploy", Line: 4, Owners: []string{"deployer"}},
+			{Section: "Security", Approvals: 2, Pattern: "/terraform/deploy", Line: 6, Owners: []string{"alice", "bob"}},
+		}, rules.OwnersFor("terraform/deploy/eu"))
+	})
+
+	t.Run("Skips sections without a match", func(t *testing.T) {
+		assert.Equal(t, []Requirement{
+			{Section: defaultSectionName, Pattern: "/terraform", Line: 3, Owners: []string{"infra"}},
+		}, rules.OwnersFor("terraform/provision"))
+	})
+
+	t.Run("Includes optional sections", func(t *testing.T) {
+		assert.Equal(t, []Requirement{
+			{Section: defaultSectionName, Pattern: "*", Line: 2, Owners: []string{"admin"}},
+			{Section: "Docs", Optional: true, Pattern: "/docs", Line: 8, Owners: []string{"writer"}},
+		}, rules.OwnersFor("docs"))
+	})
+
+	t.Run("Wildcard patterns own the files directly inside a directory", func(t *testing.T) {
+		rules, err := ParseCodeOwners(strings.NewReader("/terraform/ @infra\n/terraform/* @juniors\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []Requirement{
+			{Section: defaultSectionName, Pattern: "/terraform/*", Line: 2, Owners: []string{"juniors"}},
+		}, rules.OwnersFor("terraform"))
+		assert.Equal(t, []Requirement{
+			{Section: defaultSectionName, Pattern: "/terraform/", Line: 1, Owners: []string{"infra"}},
+		}, rules.OwnersFor("terraform/sandbox"))
+		assert.Equal(t, []Requirement{
+			{Section: defaultSectionName, Pattern: "/terraform/*", Line: 2, Owners: []string{"juniors"}},
+		}, rules.OwnersForFile("terraform/main.tf"))
+		assert.Equal(t, []Requirement{
+			{Section: defaultSectionName, Pattern: "/terraform/", Line: 1, Owners: []string{"infra"}},
+		}, rules.OwnersForFile("terraform/sandbox/main.tf"))
+	})
+
+	t.Run("Evaluated paths are directories only for the Terraform path", func(t *testing.T) {
+		rules, err := ParseCodeOwners(strings.NewReader("* @admin\n*.md @writer\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "admin", rules.OwnersForPath("./docs/", "docs")[0].Owners[0])
+		assert.Equal(t, "writer", rules.OwnersForPath("docs/README.md", "docs")[0].Owners[0])
+	})
+
+	t.Run("Empty ruleset has no owners", func(t *testing.T) {
+		assert.Empty(t, (&Ruleset{}).OwnersFor("terraform"))
+	})
+}
+
+func TestRuleset_GroupOwners(t *testing.T) {
+	rules, err := ParseCodeOwners(strings.NewReader(`
+/terraform @platform/sre @alice @platform/sre
+[Security]
+/terraform @devops
+/docs @docs/writers
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"platform/sre", "alice", "devops"}, rules.UnresolvedOwners(rules.OwnersFor("terraform")))
+
+	rules.SetGroupMembers("platform/sre", []string{"bob"})
+	rules.SetUserOwner("alice")
+	assert.Equal(t, []string{"devops"}, rules.UnresolvedOwners(rules.OwnersFor("terraform")))
+
+	rules.SetGroupMembers("devops", []string{"carol"})
+	assert.Empty(t, rules.UnresolvedOwners(rules.OwnersFor("terraform")))
+
+	assert.True(t, rules.isOwner([]string{"platform/sre"}, "bob"))
+	assert.True(t, rules.isOwner([]string{"devops"}, "carol"))
+	assert.True(t, rules.isOwner([]string{"alice"}, "alice"))
+	assert.False(t, rules.isOwner([]string{"devops"}, "bob"))
+}
+
+func TestSplitFields(t *testing.T) {
+	testCases := []struct {
+		line string
+		want []string
+	}{
+		{"/terraform @a @b", []string{"/terraform", "@a", "@b"}},
+		{"  /terraform\t@a  ", []string{"/terraform", "@a"}},
+		{`/docs\ folder @a`, []string{`/docs\ folder`, "@a"}},
+		{"", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			assert.Equal(t, tc.want, splitFields(tc.line))
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package processor
+
+// Reaction verdicts reported in a Decision.
+const (
+	// VerdictApproved marks a reaction that counted towards at least one section.
+	VerdictApproved = "approved"
+	// VerdictBlocked marks a reaction that vetoed the apply.
+	VerdictBlocked = "blocked"
+	// VerdictRejected marks a reaction that did not affect the decision.
+	VerdictRejected = "rejected"
+)
+
+// Decision is the outcome of checking merge request approvals against the
+// CODEOWNERS rules for a path, along with the details explaining it.
+type Decision struct {
+	Path string `json:"path"`
+	// Paths lists every path evaluated in changed-files mode.
+	Paths    []string `json:"paths,omitempty"`
+	Approved bool     `json:"approved"`
+	// Found is the number of approvals counted towards the requirements.
+	Found int `json:"found"`
+	// Required is the number of approvals needed for the decision to pass.
+	Required int             `json:"required"`
+	Sections []SectionResult `json:"sections"`
+	// BlockedBy lists the owners who vetoed the apply with a blocking emoji.
+	// A blocked decision is never approved, regardless of the approvals found.
+	BlockedBy []string `json:"blocked_by"`
+	// Reactions holds a verdict for every reaction on the merge request.
+	Reactions []ReactionVerdict `json:"reactions"`
+}
+
+// SectionResult reports the approvals received by a single matching section.
+type SectionResult struct {
+	Requirement
+	// Required is the effective approval count, after applying the global default.
+	Required int `json:"required"`
+	// Members lists the resolved members of every group owner.
+	Members map[string][]string `json:"members,omitempty"`
+	// Approvers lists the distinct owners whose approval counted for the section.
+	Approvers []string `json:"approvers"`
+	// Paths lists the evaluated paths the section applies to in changed-files mode.
+	Paths []string `json:"paths,omitempty"`
+}
+
+// ReactionVerdict explains whether and why a single reaction counted.
+// Source is set instead of Emoji for approvals from other sources than emojis.
+type ReactionVerdict struct {
+	User    string `json:"user"`
+	Emoji   string `json:"emoji,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Verdict string `json:"verdict"`
+	Reason  string `json:"reason"`
+}
+
+// Satisfied reports whether the section received enough distinct approvals.
+func (s SectionResult) Satisfied() bool {
+	return len(s.Approvers) >= s.Required
+}
+
+// Missing returns the sections that still need approvals.
+func (d *Decision) Missing() []SectionResult {
+	var missing []SectionResult
+	for _, section := range d.Sections {
+		if !section.Optional && !section.Satisfied() {
+			missing = append(missing, section)
+		}
+	}
+	return missing
+}
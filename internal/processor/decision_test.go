@@ -0,0 +1,23 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecision_Missing(t *testing.T) {
+	decision := &Decision{
+		Sections: []SectionResult{
+			{Requirement: Requirement{Section: "Approved"}, Required: 1, Approvers: []string{"alice"}},
+			{Requirement: Requirement{Section: "Pending"}, Required: 2, Approvers: []string{"bob"}},
+			{Requirement: Requirement{Section: "Optional", Optional: true}, Required: 1},
+		},
+	}
+
+	missing := decision.Missing()
+	assert.Len(t, missing, 1)
+	assert.Equal(t, "Pending", missing[0].Section)
+	assert.False(t, missing[0].Satisfied())
+	assert.True(t, decision.Sections[0].Satisfied())
+}
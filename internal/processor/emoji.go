@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+)
+
+// skinToneSuffix matches the suffix GitLab appends to skin-tone emoji variants,
+// e.g. "thumbsup_tone3".
+var skinToneSuffix = regexp.MustCompile(`_tone[1-5]$`)
+
+// normalizeEmoji returns the emoji name to compare against the configuration,
+// folding skin-tone variants into their base emoji when normalization is enabled.
+func normalizeEmoji(name string, cfg config.Config) string {
+	if cfg.NormalizeSkinTones {
+		return skinToneSuffix.ReplaceAllString(name, "")
+	}
+	return name
+}
+
+// approvalScope reports whether the reaction counts as an approval and, if so,
+// the CODEOWNERS section it is limited to. An empty scope means the approval
+// applies to every section. Reactions from other sources than emojis, such as
+// native approvals, always count as an approval for every section.
+func approvalScope(reaction *client.AwardEmoji, cfg config.Config) (string, bool) {
+	if reaction.Source != "" {
+		return "", true
+	}
+	name := normalizeEmoji(reaction.Name, cfg)
+	if scope, ok := cfg.ApproveEmojiScopes[name]; ok {
+		return scope, true
+	}
+	return "", slices.Contains(cfg.ApproveEmojis, name)
+}
+
+// IsApproval reports whether the reaction counts as an approval, either as a
+// configured approval emoji, a merge request approval or an approval comment.
+func IsApproval(reaction *client.AwardEmoji, cfg config.Config) bool {
+	_, ok := approvalScope(reaction, cfg)
+	return ok
+}
+
+// IsBlockingEmoji reports whether the emoji is one of the configured blocking emojis.
+func IsBlockingEmoji(name string, cfg config.Config) bool {
+	return slices.Contains(cfg.BlockEmoji, normalizeEmoji(name, cfg))
+}
+
+// approver is a user who approved the merge request, together with the
+// grants of each of their approvals.
+type approver struct {
+	username string
+	grants   []grant
+}
+
+// grant is what a single approval applies to: the section it is limited to,
+// if any, and the directories it is limited to, if any.
+type grant struct {
+	scope string
+	paths []string
+}
+
+// covers reports whether the approver's approvals apply to the section for
+// every one of the paths it matches.
+func (a *approver) covers(section string, paths []string) bool {
+	for _, filePath := range paths {
+		if !slices.ContainsFunc(a.grants, func(g grant) bool { return g.covers(section, filePath) }) {
+			return false
+		}
+	}
+	return len(paths) > 0
+}
+
+// covers reports whether the approval applies to the section for the path.
+func (g grant) covers(section, filePath string) bool {
+	if g.scope != "" && !strings.EqualFold(g.scope, section) {
+		return false
+	}
+	if len(g.paths) == 0 {
+		return true
+	}
+	parts := splitPath(filePath)
+	return slices.ContainsFunc(g.paths, func(dir string) bool {
+		prefix := splitPath(dir)
+		return len(prefix) <= len(parts) && slices.Equal(prefix, parts[:len(prefix)])
+	})
+}
@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/shini4i/atlantis-emoji-gate/internal/client"
+	"github.com/shini4i/atlantis-emoji-gate/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// emoji returns an emoji reaction with the given name.
+func emoji(name string) *client.AwardEmoji {
+	return &client.AwardEmoji{Name: name}
+}
+
+func TestApprovalScope(t *testing.T) {
+	cfg := config.Config{
+		ApproveEmojis:      []string{"thumbsup", "white_check_mark"},
+		ApproveEmojiScopes: map[string]string{"lock": "Security"},
+	}
+	normalizedCfg := cfg
+	normalizedCfg.NormalizeSkinTones = true
+
+	testCases := []struct {
+		name      string
+		reaction  *client.AwardEmoji
+		cfg       config.Config
+		wantScope string
+		wantOK    bool
+	}{
+		{"Configured emoji", emoji("thumbsup"), cfg, "", true},
+		{"Second configured emoji", emoji("white_check_mark"), cfg, "", true},
+		{"Unknown emoji", emoji("rocket"), cfg, "", false},
+		{"Scoped emoji", emoji("lock"), cfg, "Security", true},
+		{"Skin tone rejected without normalization", emoji("thumbsup_tone3"), cfg, "", false},
+		{"Skin tone accepted with normalization", emoji("thumbsup_tone3"), normalizedCfg, "", true},
+		{"Scoped skin tone keeps its scope", emoji("lock_tone1"), normalizedCfg, "Security", true},
+		{"Only valid tones are normalized", emoji("thumbsup_tone9"), normalizedCfg, "", false},
+		{"Merge request approval", &client.AwardEmoji{Source: config.ApprovalSourceMergeRequest}, config.Config{}, "", true},
+		{"Approval comment", &client.AwardEmoji{Source: config.ApprovalSourceComment}, config.Config{}, "", true},
+		{"Emoji named like a source", emoji("merge_request_approval"), cfg, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, ok := approvalScope(tc.reaction, tc.cfg)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantScope, scope)
+		})
+	}
+}
+
+func TestIsApproval(t *testing.T) {
+	cfg := config.Config{ApproveEmojis: []string{"thumbsup"}}
+	assert.True(t, IsApproval(emoji("thumbsup"), cfg))
+	assert.True(t, IsApproval(&client.AwardEmoji{Source: config.ApprovalSourceMergeRequest}, cfg))
+	assert.False(t, IsApproval(emoji("thumbsdown"), cfg))
+	assert.False(t, IsApproval(emoji("comment_approval"), cfg))
+}
+
+func TestIsBlockingEmoji(t *testing.T) {
+	cfg := config.Config{BlockEmoji: []string{"thumbsdown"}}
+	assert.True(t, IsBlockingEmoji("thumbsdown", cfg))
+	assert.False(t, IsBlockingEmoji("thumbsdown_tone2", cfg))
+	assert.False(t, IsBlockingEmoji("thumbsup", cfg))
+
+	cfg.NormalizeSkinTones = true
+	assert.True(t, IsBlockingEmoji("thumbsdown_tone2", cfg))
+}
+
+func TestApprover_Covers(t *testing.T) {
+	paths := []string{"terraform/deploy"}
+
+	scoped := &approver{username: "alice", grants: []grant{{scope: "Security"}}}
+	assert.True(t, scoped.covers("security", paths))
+	assert.False(t, scoped.covers("Platform", paths))
+
+	unscoped := &approver{username: "bob", grants: []grant{{}}}
+	assert.True(t, unscoped.covers("Platform", paths))
+
+	limited := &approver{username: "carol", grants: []grant{{paths: []string{"terraform/deploy"}}}}
+	assert.True(t, limited.covers("Platform", []string{"terraform/deploy", "terraform/deploy/main.tf"}))
+	assert.False(t, limited.covers("Platform", []string{"terraform/deploy", "modules/vpc/main.tf"}))
+	assert.False(t, limited.covers("Platform", []string{"terraform/deployment"}))
+
+	merged := &approver{username: "dave", grants: []grant{{paths: []string{"terraform"}}, {paths: []string{"./modules/"}}}}
+	assert.True(t, merged.covers("Platform", []string{"terraform/deploy", "modules/vpc/main.tf"}))
+}
@@ -0,0 +1,180 @@
+package processor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// globstar is the pattern segment that matches zero or more path segments.
+const globstar = "**"
+
+// anyName is the path segment standing for any file name. It cannot occur in
+// a path, so it only matches pattern segments such as "*" that match every name.
+const anyName = "\x00"
+
+// pattern is a compiled CODEOWNERS path pattern.
+//
+// Matching follows GitLab's CODEOWNERS rules, which are based on gitignore:
+//   - A leading slash anchors the pattern to the repository root. Patterns
+//     without one match at any depth, as if they were prefixed with "**/".
+//   - A trailing slash marks a directory, which the pattern owns along with
+//     everything beneath it.
+//   - A pattern whose last segment has no wildcard names a directory too, so
+//     "/terraform" owns "terraform/deploy/main.tf".
+//   - Otherwise the pattern must match the whole file path: "*" and "?" never
+//     cross a slash, so "/terraform/*" owns "terraform/main.tf" but not
+//     "terraform/deploy/main.tf".
+//   - "**" matches zero or more directories, and character classes may be
+//     negated with either "[!...]" or "[^...]".
+//
+// GitLab matches patterns against file paths. A directory such as
+// REPO_REL_DIR is owned by the patterns owning any file directly inside it,
+// so "/terraform/*" owns "terraform" but not "terraform/deploy", while
+// "*.md" owns neither.
+//   - A backslash escapes the next character, e.g. "\#" or "\ ".
+type pattern struct {
+	raw      string
+	segments []string
+	// directory is set when the pattern also owns everything beneath a match.
+	directory bool
+	// dirOnly is set for patterns with a trailing slash, which match
+	// directories only.
+	dirOnly bool
+}
+
+// compilePattern parses a raw CODEOWNERS pattern and validates its glob syntax.
+func compilePattern(raw string) (*pattern, error) {
+	normalized := raw
+	dirOnly := strings.HasSuffix(raw, "/")
+	if normalized == "*" {
+		normalized = "/" + globstar
+	}
+	if !strings.HasPrefix(normalized, "/") {
+		normalized = "/" + globstar + "/" + normalized
+	}
+	normalized = strings.TrimSuffix(strings.TrimPrefix(normalized, "/"), "/")
+
+	var segments []string
+	for _, segment := range strings.Split(normalized, "/") {
+		if segment == "" {
+			continue
+		}
+		// Consecutive globstars are equivalent to a single one.
+		if segment == globstar && len(segments) > 0 && segments[len(segments)-1] == globstar {
+			continue
+		}
+		segment = translateNegation(segment)
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", raw, err)
+		}
+		segments = append(segments, segment)
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid pattern '%s': pattern is empty", raw)
+	}
+
+	directory := dirOnly || !hasWildcard(segments[len(segments)-1])
+	return &pattern{raw: raw, segments: segments, directory: directory, dirOnly: dirOnly}, nil
+}
+
+// hasWildcard reports whether a pattern segment contains an unescaped "*",
+// "?" or character class.
+func hasWildcard(segment string) bool {
+	for i := 0; i < len(segment); i++ {
+		switch segment[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// translateNegation rewrites fnmatch-style negated character classes ("[!a]")
+// into the "[^a]" form understood by path.Match.
+func translateNegation(segment string) string {
+	var b strings.Builder
+	escaped := false
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		b.WriteByte(c)
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '[' && i+1 < len(segment) && segment[i+1] == '!':
+			b.WriteByte('^')
+			i++
+		}
+	}
+	return b.String()
+}
+
+// matchFile reports whether the repository-relative file path is owned by the
+// pattern, either because it matches directly or, for directory patterns,
+// because one of its parent directories does.
+func (p *pattern) matchFile(filePath string) bool {
+	return p.matchParts(splitPath(filePath))
+}
+
+// matchDir reports whether the repository-relative directory is owned by the
+// pattern, because it owns any file directly inside it.
+func (p *pattern) matchDir(dir string) bool {
+	return p.matchParts(append(splitPath(dir), anyName))
+}
+
+// matchParts reports whether the path segments are owned by the pattern.
+func (p *pattern) matchParts(parts []string) bool {
+	if p.directory {
+		// The last segment is a file inside the owned directory.
+		for i := 1; i < len(parts); i++ {
+			if matchSegments(p.segments, parts[:i]) {
+				return true
+			}
+		}
+	}
+	return !p.dirOnly && matchSegments(p.segments, parts)
+}
+
+// splitPath cleans a repository-relative path and splits it into segments.
+// The repository root ("." or "/") yields no segments.
+func splitPath(filePath string) []string {
+	cleaned := strings.Trim(path.Clean("/"+filePath), "/")
+	if cleaned == "" {
+		return nil
+	}
+	return strings.Split(cleaned, "/")
+}
+
+// matchSegments reports whether the pattern segments match all path segments.
+func matchSegments(patternSegments, pathSegments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(pathSegments) == 0
+	}
+
+	if patternSegments[0] == globstar {
+		// Let the globstar consume zero or more path segments.
+		for i := 0; i <= len(pathSegments); i++ {
+			if matchSegments(patternSegments[1:], pathSegments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(pathSegments) == 0 {
+		return false
+	}
+
+	if pathSegments[0] == anyName {
+		return len(pathSegments) == 1 && strings.Trim(patternSegments[0], "*") == "" && matchSegments(patternSegments[1:], nil)
+	}
+
+	// Syntax errors are rejected by compilePattern, so the error can be ignored.
+	matched, _ := path.Match(patternSegments[0], pathSegments[0])
+	return matched && matchSegments(patternSegments[1:], pathSegments[1:])
+}
@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPatternMatchFile is a conformance suite for GitLab CODEOWNERS path
+// matching of files, such as the files changed by a merge request.
+func TestPatternMatchFile(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// Catch-all.
+		{"*", "main.tf", true},
+		{"*", "terraform/deploy/main.tf", true},
+		{"/**", "terraform/deploy/main.tf", true},
+
+		// Anchored directory prefixes.
+		{"/terraform", "terraform/main.tf", true},
+		{"/terraform", "terraform/deploy/eu-west-1/main.tf", true},
+		{"/terraform", "terraform-modules/main.tf", false},
+		{"/terraform", "infra/terraform/main.tf", false},
+		{"/terraform/deploy", "terraform/deploy/main.tf", true},
+		{"/terraform/deploy", "terraform/provision/main.tf", false},
+
+		// Trailing-slash directories.
+		{"/terraform/", "terraform/main.tf", true},
+		{"/terraform/", "terraform/deploy/main.tf", true},
+		{"/terraform/", "terraformx/main.tf", false},
+		{"terraform/", "infra/terraform/deploy/main.tf", true},
+		{"/terraform/*/", "terraform/deploy/eu/main.tf", true},
+		{"/terraform/*/", "terraform/main.tf", false},
+
+		// Unanchored patterns match at any level.
+		{"deploy", "deploy/main.tf", true},
+		{"deploy", "terraform/deploy/eu/main.tf", true},
+		{"deploy", "terraform/deployment/main.tf", false},
+		{"terraform/deploy", "infra/terraform/deploy/main.tf", true},
+		{"*.tf", "main.tf", true},
+		{"*.tf", "modules/vpc/main.tf", true},
+		{"*.tf", "modules/vpc/main.tfvars", false},
+
+		// Single-segment wildcards never cross a slash.
+		{"/terraform/*", "terraform/main.tf", true},
+		{"/terraform/*", "terraform/deploy/main.tf", false},
+		{"/terraform/*/main.tf", "terraform/deploy/main.tf", true},
+		{"/terraform/*/main.tf", "terraform/deploy/eu/main.tf", false},
+		{"/terraform/dep?oy.tf", "terraform/deploy.tf", true},
+		{"/terraform/dep?oy.tf", "terraform/depoy.tf", false},
+		{"/terraform/[dp]*", "terraform/provision.tf", true},
+		{"/terraform/[dp]*", "terraform/provision/main.tf", false},
+		{"/terraform/[!dp]*", "terraform/provision.tf", false},
+		{"/terraform/[^dp]*", "terraform/provision.tf", false},
+		{"/terraform/[!dp]*", "terraform/shared.tf", true},
+		{`/terraform/\[!dp]*`, "terraform/[!dp]x", true},
+
+		// Globstars at any depth.
+		{"/**/deploy", "deploy/main.tf", true},
+		{"/**/deploy", "a/b/c/deploy/main.tf", true},
+		{"/terraform/**/eu", "terraform/eu/main.tf", true},
+		{"/terraform/**/eu", "terraform/a/b/c/eu/main.tf", true},
+		{"/terraform/**/eu", "terraform/a/b/c/us/main.tf", false},
+		{"/terraform/**", "terraform/deploy/main.tf", true},
+		{"/terraform/**", "other/deploy/main.tf", false},
+		{"/terraform/**/*.tf", "terraform/a/b/main.tf", true},
+		{"**/deploy/**", "terraform/deploy/eu/main.tf", true},
+		{"/terraform/**/**/eu", "terraform/eu/main.tf", true},
+
+		// Dot files and directories are matched like any other name.
+		{"/.gitlab", ".gitlab/ci.yml", true},
+		{"/*", ".gitlab-ci.yml", true},
+		{"/*", ".gitlab/ci.yml", false},
+
+		// Escaped characters.
+		{`/\#infra`, "#infra/main.tf", true},
+		{`/docs\ folder`, "docs folder/index.md", true},
+		{`/docs\ folder`, "docs/index.md", false},
+		{`/star\*`, "star*", true},
+		{`/star\*`, "stars", false},
+		{`/star\*`, "star*/nested", true},
+
+		// Paths are normalized before matching.
+		{"/terraform/deploy", "./terraform/deploy/main.tf", true},
+		{"/terraform/deploy", "/terraform/deploy/main.tf", true},
+		{"/terraform/deploy", "terraform//deploy/main.tf", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" -> "+tc.path, func(t *testing.T) {
+			p, err := compilePattern(tc.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, p.matchFile(tc.path))
+		})
+	}
+}
+
+// TestPatternMatchDir covers directories such as REPO_REL_DIR, which are owned
+// by the patterns owning any file directly inside them.
+func TestPatternMatchDir(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		dir     string
+		want    bool
+	}{
+		// Catch-all.
+		{"*", "terraform", true},
+		{"*", "terraform/deploy", true},
+		{"*", ".", true},
+		{"/**", "terraform/deploy", true},
+
+		// Directory patterns own the directory and everything beneath it.
+		{"/terraform", "terraform", true},
+		{"/terraform", "terraform/deploy", true},
+		{"/terraform", "terraform-modules", false},
+		{"/terraform", "infra/terraform", false},
+		{"/terraform", ".", false},
+		{"/terraform/deploy", "terraform/deploy", true},
+		{"/terraform/deploy", "terraform", false},
+		{"/terraform/", "terraform", true},
+		{"/terraform/", "terraform/deploy", true},
+		{"terraform/", "infra/terraform/deploy", true},
+		{"deploy", "terraform/deploy/eu", true},
+		{"deploy", "terraform/deployment", false},
+		{"/terraform/*/eu", "terraform/deploy/eu", true},
+		{"/terraform/*/eu", "terraform/deploy", false},
+
+		// Wildcard patterns own the files directly inside a directory.
+		{"/terraform/*", "terraform", true},
+		{"/terraform/*", "terraform/sandbox", false},
+		{"/*", ".", true},
+		{"/*", ".gitlab", false},
+		{"/terraform/**", "terraform", true},
+		{"/terraform/**", "terraform/deploy/eu", true},
+		{"**/deploy/**", "terraform/deploy", true},
+
+		// Patterns matching only some file names own no directory.
+		{"*.tf", "terraform", false},
+		{"/terraform/dep?oy", "terraform", false},
+		{"/terraform/dep?oy", "terraform/deploy", false},
+		{"/terraform/[dp]*", "terraform/provision", false},
+
+		// Paths are normalized before matching.
+		{"/terraform/deploy", "./terraform/deploy/", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" -> "+tc.dir, func(t *testing.T) {
+			p, err := compilePattern(tc.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, p.matchDir(tc.dir))
+		})
+	}
+}
+
+func TestCompilePattern_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		wantErr string
+	}{
+		{"Unclosed character class", "[abc", "invalid pattern"},
+		{"Trailing escape", `/docs\`, "invalid pattern"},
+		{"Root only", "/", "pattern is empty"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := compilePattern(tc.pattern)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}